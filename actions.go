@@ -0,0 +1,135 @@
+package golarm
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// ActionInterval is the minimum time between two executions of the actions of an alarm,
+// so a crash-looping process isn't acted on repeatedly
+var ActionInterval = time.Minute
+
+// action acts on the process with the given PID when the alarm is fired
+type action func(pid int) error
+
+// addAction adds an action to the alarm.
+// Actions acting on the PID can't be used when the process exited, as it is gone or its PID may be reused
+func addAction(a *Alarm, f action, targetsPid bool) {
+	if a.Err == nil {
+		if a.jobType != procAlarm || isGroup(a) || (targetsPid && a.stats.metric == exitedMetric) {
+			a.Err = ErrIncorrectTypeForAction
+			return
+		}
+		if err := validate(a); err != nil {
+			a.Err = err
+			return
+		}
+		a.actions = append(a.actions, f)
+	}
+}
+
+// remediate executes the actions on the process found in the last check, unless they were executed recently
+func remediate(a *Alarm) {
+	pid := int(a.stats.proc.pid)
+	if len(a.actions) == 0 || pid == 0 {
+		return
+	}
+
+	interval := a.interval
+	if interval == 0 {
+		interval = ActionInterval
+	}
+	if !a.acted.IsZero() && a.metricsManager.now().Sub(a.acted) < interval {
+		return
+	}
+	a.acted = a.metricsManager.now()
+
+	for _, f := range a.actions {
+		if err := f(pid); err != nil && a.actionError != nil {
+			a.actionError(err)
+		}
+	}
+}
+
+// sameProcess checks that the PID still belongs to the process started at the given time, so it wasn't reused
+func sameProcess(manager sigarMetrics, pid int, started uint64) bool {
+	t, err := manager.getProcTime(pid)
+	return err == nil && t.StartTime == started
+}
+
+// Signal sends the signal to the process when the alarm is fired
+func (j *Alarm) Signal(sig syscall.Signal) *Alarm {
+	addAction(j, func(pid int) error {
+		return syscall.Kill(pid, sig)
+	}, true)
+	return j
+}
+
+// Renice changes the priority of the process when the alarm is fired
+func (j *Alarm) Renice(priority int) *Alarm {
+	addAction(j, func(pid int) error {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, pid, priority)
+	}, true)
+	return j
+}
+
+// Kill terminates the process when the alarm is fired, killing it if it is still alive after the grace period.
+// The process isn't killed if its PID was given to another one during the grace period
+func (j *Alarm) Kill(grace time.Duration) *Alarm {
+	if j.Err == nil && grace <= 0 {
+		(*j).Err = ErrIncorrectGrace
+		return j
+	}
+	addAction(j, func(pid int) error {
+		manager := j.metricsManager
+		t, err := manager.getProcTime(pid)
+		if err != nil {
+			return err
+		}
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			return err
+		}
+		time.AfterFunc(grace, func() {
+			if sameProcess(manager, pid, t.StartTime) {
+				syscall.Kill(pid, syscall.SIGKILL)
+			}
+		})
+		return nil
+	}, true)
+	return j
+}
+
+// Restart runs the command when the alarm is fired, without waiting for it to finish
+func (j *Alarm) Restart(command string, args ...string) *Alarm {
+	addAction(j, func(pid int) error {
+		cmd := exec.Command(command, args...)
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		go cmd.Wait()
+		return nil
+	}, false)
+	return j
+}
+
+// OnActionError allows a func to be specified.
+// This callback will be executed with the error of every action that fails
+func (j *Alarm) OnActionError(f func(error)) *Alarm {
+	if j.Err == nil {
+		(*j).actionError = f
+	}
+	return j
+}
+
+// Throttle sets the minimum time between two executions of the actions of the alarm, ActionInterval by default
+func (j *Alarm) Throttle(interval time.Duration) *Alarm {
+	if j.Err == nil {
+		if interval <= 0 {
+			(*j).Err = ErrIncorrectInterval
+			return j
+		}
+		(*j).interval = interval
+	}
+	return j
+}
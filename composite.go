@@ -0,0 +1,79 @@
+package golarm
+
+type operator int
+
+const (
+	operatorNotDefined operator = iota
+	allOperator
+	anyOperator
+	notOperator
+	addOperator
+	subOperator
+	mulOperator
+	divOperator
+)
+
+func newComposite(o operator, alarms []*Alarm) *Alarm {
+	a := &Alarm{
+		jobType: compositeAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result:   make(chan bool),
+		operator: o,
+		children: alarms,
+	}
+
+	if len(alarms) == 0 {
+		a.Err = ErrEmptyComposite
+	}
+
+	for _, c := range alarms {
+		if c.Err != nil {
+			a.Err = c.Err
+			break
+		}
+		if err := validate(c); err != nil {
+			a.Err = err
+			break
+		}
+	}
+	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// All creates an alarm that fires when all the given alarms are fired at the same check.
+// The given alarms don't need a callback, they are evaluated as part of the new one
+func All(alarms ...*Alarm) *Alarm {
+	return newComposite(allOperator, alarms)
+}
+
+// Any creates an alarm that fires when any of the given alarms is fired
+func Any(alarms ...*Alarm) *Alarm {
+	return newComposite(anyOperator, alarms)
+}
+
+// Not creates an alarm that fires when the given alarm is not fired
+func Not(alarm *Alarm) *Alarm {
+	return newComposite(notOperator, []*Alarm{alarm})
+}
+
+// combine evaluates every child against the same check, so all of them keep their samples updated
+func combine(a *Alarm) bool {
+	fired := 0
+	for _, c := range a.children {
+		if evaluate(c) {
+			fired++
+		}
+	}
+
+	switch a.operator {
+	case allOperator:
+		return fired == len(a.children)
+	case anyOperator:
+		return fired > 0
+	case notOperator:
+		return fired == 0
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package golarm
+
+func isOperand(a *Alarm) bool {
+	switch a.jobType {
+	case expressionAlarm, loadAlarm, uptimeAlarm:
+	case memoryAlarm, swapAlarm, procAlarm, processesAlarm, networkAlarm, tcpAlarm, diskAlarm:
+		if (a.stats.metric == 0 && a.stats.proc.aggregation != countAggregation) || isStatus(a) {
+			return false
+		}
+	default:
+		return false
+	}
+	return a.comparison == comparisonNotDefined && a.modifier == modifierNotDefined
+}
+
+func newExpression(o operator, left, right *Alarm) *Alarm {
+	a := &Alarm{
+		jobType: expressionAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result:   make(chan bool),
+		operator: o,
+		children: []*Alarm{left, right},
+	}
+
+	switch {
+	case left.Err != nil:
+		a.Err = left.Err
+	case right.Err != nil:
+		a.Err = right.Err
+	case !isOperand(left) || !isOperand(right):
+		a.Err = ErrIncorrectOperand
+	}
+	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// Add creates an alarm based on the sum of both metrics, e.g. SystemSwap().Used().Add(SystemMemory().Used())
+func (j *Alarm) Add(b *Alarm) *Alarm {
+	return newExpression(addOperator, j, b)
+}
+
+// Sub creates an alarm based on the difference between both metrics
+func (j *Alarm) Sub(b *Alarm) *Alarm {
+	return newExpression(subOperator, j, b)
+}
+
+// Mul creates an alarm based on the product of both metrics
+func (j *Alarm) Mul(b *Alarm) *Alarm {
+	return newExpression(mulOperator, j, b)
+}
+
+// Div creates an alarm based on the quotient of both metrics, being 0 when dividing by 0
+func (j *Alarm) Div(b *Alarm) *Alarm {
+	return newExpression(divOperator, j, b)
+}
+
+func calculate(a *Alarm) (float64, bool) {
+	left, measured := measure(a.children[0])
+	if !measured {
+		return 0.0, false
+	}
+	right, measured := measure(a.children[1])
+	if !measured {
+		return 0.0, false
+	}
+
+	switch a.operator {
+	case addOperator:
+		return left + right, true
+	case subOperator:
+		return left - right, true
+	case mulOperator:
+		return left * right, true
+	case divOperator:
+		if right == 0 {
+			return 0.0, true
+		}
+		return left / right, true
+	}
+	return 0.0, true
+}
@@ -1,12 +1,154 @@
 package golarm
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
 	t "time"
 
 	"github.com/cloudfoundry/gosigar"
 )
 
 type fakeSigar struct {
+	// bytes added to the used memory (and removed from the free one) of the system and processes
+	leaked uint64
+	// state of the processes, running when not set
+	state sigar.RunState
+	// command line of the running processes by PID, only the current process when not set
+	procs map[int][]string
+	// milliseconds of CPU added to the processes
+	cpu uint64
+	// start time of the processes in milliseconds since the epoch
+	procStart uint64
+	// owner of the processes by PID, the one in the procfs fixture when not set
+	uids map[int]int
+	// parent of the processes by PID, 500 when not set
+	parents map[int]int
+	// bytes read and written by the processes
+	io uint64
+	// major page faults of the processes
+	faults uint64
+	// bytes, packets, errors and drops added to the counters of the network interfaces
+	traffic uint64
+	// operations, sectors and milliseconds added to the counters of the disks
+	disk uint64
+	// the sockets of the processes can't be read, like the ones of other users without root
+	denied bool
+	// current time, for driving alarms through time
+	clock func() t.Time
+}
+
+func (f *fakeSigar) now() t.Time {
+	if f.clock == nil {
+		return t.Now()
+	}
+	return f.clock()
+}
+
+// procfs fixtures, every process is read from the one with PID 1
+var fakeProcRoot = filepath.Join("testdata", "proc")
+
+func (f *fakeSigar) getProcFiles(pid int) (procFiles, error) {
+	return readProcFiles(fakeProcRoot, 1)
+}
+
+func (f *fakeSigar) getProcStatus(pid int) (procStatus, error) {
+	status, err := readProcStatus(fakeProcRoot, 1)
+	if uid, ok := f.uids[pid]; ok {
+		status.uid = uid
+	}
+	return status, err
+}
+
+func (f *fakeSigar) getProcSoftLimit(pid int, name string) (uint64, error) {
+	return readSoftLimit(fakeProcRoot, 1, name)
+}
+
+func (f *fakeSigar) getProcIO(pid int) (procIO, error) {
+	io, err := readProcIO(fakeProcRoot, 1)
+	io.readBytes += f.io
+	io.writeBytes += f.io
+	return io, err
+}
+
+func (f *fakeSigar) getSystemStat() (systemStat, error) {
+	return readSystemStat(fakeProcRoot)
+}
+
+func (f *fakeSigar) getPidMax() (uint64, error) {
+	return readPidMax(fakeProcRoot)
+}
+
+func (f *fakeSigar) getNetDevice(name string) (netDevice, error) {
+	device, err := readNetDevice(fakeProcRoot, name)
+	device.receivedBytes += f.traffic
+	device.receivedPackets += f.traffic
+	device.receivedErrors += f.traffic
+	device.receivedDrops += f.traffic
+	device.sentBytes += f.traffic
+	device.sentPackets += f.traffic
+	device.sentErrors += f.traffic
+	device.sentDrops += f.traffic
+	return device, err
+}
+
+func (f *fakeSigar) getSockets(protocol string) ([]socket, error) {
+	return readSockets(fakeProcRoot, protocol)
+}
+
+func (f *fakeSigar) getProcSockets(pid int) ([]uint64, error) {
+	if _, err := f.getProcArgs(pid); err != nil {
+		return nil, os.ErrNotExist
+	}
+	if f.denied {
+		return nil, os.ErrPermission
+	}
+	return readProcSockets(fakeProcRoot, 1)
+}
+
+func (f *fakeSigar) getDiskStats(name string) (diskStats, error) {
+	disk, err := readDiskStats(fakeProcRoot, name)
+	disk.reads += f.disk
+	disk.readSectors += f.disk
+	disk.readTime += f.disk
+	disk.writes += f.disk
+	disk.writtenSectors += f.disk
+	disk.writeTime += f.disk
+	disk.ioTime += f.disk
+	return disk, err
+}
+
+func (f *fakeSigar) getMeminfo() (map[string]uint64, error) {
+	return readMeminfo(fakeProcRoot)
+}
+
+func (f *fakeSigar) getCpuList() (sigar.CpuList, error) {
+	return sigar.CpuList{List: make([]sigar.Cpu, 4)}, nil
+}
+
+func (f *fakeSigar) getProcList() (sigar.ProcList, error) {
+	if f.procs == nil {
+		return sigar.ProcList{List: []int{os.Getpid()}}, nil
+	}
+
+	list := make([]int, 0, len(f.procs))
+	for pid := range f.procs {
+		list = append(list, pid)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(list)))
+	return sigar.ProcList{List: list}, nil
+}
+
+func (f *fakeSigar) getProcArgs(pid int) (sigar.ProcArgs, error) {
+	if f.procs == nil {
+		return sigar.ProcArgs{List: []string{"fakeProc"}}, nil
+	}
+	args, ok := f.procs[pid]
+	if !ok {
+		return sigar.ProcArgs{}, errors.New("no such process")
+	}
+	return sigar.ProcArgs{List: args}, nil
 }
 
 func (f *fakeSigar) CollectCpuStats(collectionInterval t.Duration) (<-chan sigar.Cpu, chan<- struct{}) {
@@ -24,10 +166,10 @@ func (f *fakeSigar) GetLoadAverage() (sigar.LoadAverage, error) {
 func (f *fakeSigar) GetMem() (sigar.Mem, error) {
 	return sigar.Mem{
 		Total:      100000000,
-		Used:       20000000,
-		Free:       80000000,
-		ActualFree: 80000000,
-		ActualUsed: 20000000,
+		Used:       20000000 + f.leaked,
+		Free:       80000000 - f.leaked,
+		ActualFree: 80000000 - f.leaked,
+		ActualUsed: 20000000 + f.leaked,
 	}, nil
 }
 
@@ -51,10 +193,22 @@ func (f *fakeSigar) GetFileSystemUsage(string) (sigar.FileSystemUsage, error) {
 }
 
 func (f *fakeSigar) getProcState(pid int) (sigar.ProcState, error) {
+	state := f.state
+	if state == 0 {
+		state = sigar.RunStateRun
+	}
+	args, err := f.getProcArgs(pid)
+	if err != nil {
+		return sigar.ProcState{}, err
+	}
+	ppid, ok := f.parents[pid]
+	if !ok {
+		ppid = 500
+	}
 	return sigar.ProcState{
-		Name:      "fakeProc",
-		State:     sigar.RunStateRun,
-		Ppid:      500,
+		Name:      comm(filepath.Base(args.List[0])),
+		State:     state,
+		Ppid:      ppid,
 		Tty:       69,
 		Priority:  1,
 		Nice:      0,
@@ -63,22 +217,33 @@ func (f *fakeSigar) getProcState(pid int) (sigar.ProcState, error) {
 }
 
 func (f *fakeSigar) getProcMem(pid int) (sigar.ProcMem, error) {
+	if _, err := f.getProcArgs(pid); err != nil {
+		return sigar.ProcMem{}, err
+	}
 	return sigar.ProcMem{
 		Size:        100000000,
-		Resident:    100000000,
+		Resident:    100000000 + f.leaked,
 		Share:       0,
 		MinorFaults: 0,
-		MajorFaults: 0,
+		MajorFaults: f.faults,
 		PageFaults:  0,
 	}, nil
 }
 
 func (f *fakeSigar) getProcTime(pid int) (sigar.ProcTime, error) {
+	if _, err := f.getProcArgs(pid); err != nil {
+		return sigar.ProcTime{}, err
+	}
+	// started two hours ago unless set
+	start := f.procStart
+	if start == 0 {
+		start = uint64(f.now().Add(-2*t.Hour).UnixNano() / int64(t.Millisecond))
+	}
 	return sigar.ProcTime{
-		StartTime: 123456,
-		User:      123456,
+		StartTime: start,
+		User:      123456 + f.cpu,
 		Sys:       123456,
-		Total:     123456,
+		Total:     123456 + f.cpu,
 	}, nil
 }
 
@@ -2,7 +2,12 @@ package golarm
 
 import (
 	"errors"
+	"math"
+	"os/user"
+	"regexp"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/carlescere/scheduler"
 	"github.com/cloudfoundry/gosigar"
@@ -27,11 +32,36 @@ var (
 	ErrMultipleComparisonDefined     = errors.New("Alarm comparison already defined")
 	ErrIncorrectTypeForAbove         = errors.New("Alarm type not set or trying to use Above with a status metric")
 	ErrIncorrectTypeForBelow         = errors.New("Alarm type not set or trying to use Below with a status metric")
-	ErrIncorrectTypeForPercentage    = errors.New("Couldn't apply percentage to uptime/status Alarms")
+	ErrIncorrectTypeForPercentage    = errors.New("Couldn't apply percentage to uptime/status/expression Alarms")
 	ErrIncorrectValuesWithPercentage = errors.New("Couldn't apply percentage")
 	ErrInexistentPid                 = errors.New("Pid does not exist")
 	ErrIncorrectTypeForComparison    = errors.New("Alarm type not set or trying to use an incorrect comparison with this type of Alarm")
 	ErrIncorrectTypeForMetric        = errors.New("Alarm type not set or trying to use an incorrect metric with this type of Alarm")
+	ErrIncorrectTypeForModifier      = errors.New("Alarm type not set or trying to use a modifier with a status metric")
+	ErrMultipleModifierDefined       = errors.New("Alarm modifier already defined")
+	ErrIncorrectWindow               = errors.New("Window must be a positive duration")
+	ErrIncorrectPercentile           = errors.New("Percentile must be greater than 0 and lower or equal than 1")
+	ErrIncorrectHorizon              = errors.New("Horizon must be a positive duration")
+	ErrIncorrectDeviation            = errors.New("Number of standard deviations must be positive")
+	ErrEmptyComposite                = errors.New("At least one alarm is needed for combining them")
+	ErrIncorrectOperand              = errors.New("Operands must be alarms with a metric and without comparisons or modifiers")
+	ErrIncorrectRange                = errors.New("Lower bound of the range must be lower or equal than the upper one")
+	ErrIncorrectTypeForTolerance     = errors.New("Comparison not set or trying to use Within with something different than Equal or NotEqual")
+	ErrIncorrectTolerance            = errors.New("Tolerance can't be negative")
+	ErrEmptyStatus                   = errors.New("At least one state is needed")
+	ErrIncorrectSelector             = errors.New("Process name, pattern or pidfile can't be empty")
+	ErrIncorrectTypeForAggregation   = errors.New("Alarm type not set or trying to aggregate something different than process metrics")
+	ErrMultipleAggregationDefined    = errors.New("Alarm aggregation already defined")
+	ErrIncorrectTypeForAction        = errors.New("Alarm type not set or trying to use an action with something different than a single process")
+	ErrInexistentUser                = errors.New("User does not exist")
+	ErrInexistentInterface           = errors.New("Network interface does not exist")
+	ErrIncorrectTypeForPort          = errors.New("Port can only be used with sockets")
+	ErrIncorrectPort                 = errors.New("Port must be between 1 and 65535")
+	ErrInexistentDisk                = errors.New("Disk does not exist")
+	ErrInexistentField               = errors.New("Memory field does not exist")
+	ErrMetricNotDefined              = errors.New("Bad chain. Alarm metric not defined")
+	ErrIncorrectGrace                = errors.New("Grace period must be a positive duration")
+	ErrIncorrectInterval             = errors.New("Interval must be a positive duration")
 )
 
 type alarmType int
@@ -39,6 +69,8 @@ type comparison int
 
 type value struct {
 	value      float64
+	upper      float64
+	tolerance  float64
 	percentage bool
 }
 
@@ -53,6 +85,19 @@ type Alarm struct {
 	comparison     comparison
 	value          value
 	stats          stats
+	modifier       modifier
+	window         time.Duration
+	percentile     float64
+	horizon        time.Duration
+	history        []sample
+	operator       operator
+	children       []*Alarm
+	noMatch        func()
+	counters       map[string]sample
+	actions        []action
+	interval       time.Duration
+	acted          time.Time
+	actionError    func(error)
 }
 
 const (
@@ -62,6 +107,9 @@ const (
 	equal
 	below
 	belowEqual
+	notEqual
+	between
+	outside
 )
 
 const (
@@ -71,6 +119,13 @@ const (
 	swapAlarm
 	uptimeAlarm
 	procAlarm
+	compositeAlarm
+	expressionAlarm
+	processesAlarm
+	networkAlarm
+	tcpAlarm
+	portAlarm
+	diskAlarm
 )
 
 type sigarMetrics interface {
@@ -78,6 +133,21 @@ type sigarMetrics interface {
 	getProcState(int) (sigar.ProcState, error)
 	getProcMem(int) (sigar.ProcMem, error)
 	getProcTime(int) (sigar.ProcTime, error)
+	getProcArgs(int) (sigar.ProcArgs, error)
+	getProcList() (sigar.ProcList, error)
+	getCpuList() (sigar.CpuList, error)
+	getProcFiles(int) (procFiles, error)
+	getProcStatus(int) (procStatus, error)
+	getProcIO(int) (procIO, error)
+	getProcSoftLimit(int, string) (uint64, error)
+	getSystemStat() (systemStat, error)
+	getPidMax() (uint64, error)
+	getNetDevice(string) (netDevice, error)
+	getSockets(string) ([]socket, error)
+	getProcSockets(int) ([]uint64, error)
+	getDiskStats(string) (diskStats, error)
+	getMeminfo() (map[string]uint64, error)
+	now() time.Time
 	getUpTime() (sigar.Uptime, error)
 }
 
@@ -109,6 +179,72 @@ func (c *concreteSigar) getProcTime(pid int) (sigar.ProcTime, error) {
 	return p, err
 }
 
+func (c *concreteSigar) getProcArgs(pid int) (sigar.ProcArgs, error) {
+	p := sigar.ProcArgs{}
+	err := p.Get(pid)
+	return p, err
+}
+
+func (c *concreteSigar) getProcList() (sigar.ProcList, error) {
+	p := sigar.ProcList{}
+	err := p.Get()
+	return p, err
+}
+
+func (c *concreteSigar) getCpuList() (sigar.CpuList, error) {
+	p := sigar.CpuList{}
+	err := p.Get()
+	return p, err
+}
+
+func (c *concreteSigar) getProcFiles(pid int) (procFiles, error) {
+	return readProcFiles(ProcRoot, pid)
+}
+
+func (c *concreteSigar) getProcStatus(pid int) (procStatus, error) {
+	return readProcStatus(ProcRoot, pid)
+}
+
+func (c *concreteSigar) getProcIO(pid int) (procIO, error) {
+	return readProcIO(ProcRoot, pid)
+}
+
+func (c *concreteSigar) getProcSoftLimit(pid int, name string) (uint64, error) {
+	return readSoftLimit(ProcRoot, pid, name)
+}
+
+func (c *concreteSigar) getSystemStat() (systemStat, error) {
+	return readSystemStat(ProcRoot)
+}
+
+func (c *concreteSigar) getPidMax() (uint64, error) {
+	return readPidMax(ProcRoot)
+}
+
+func (c *concreteSigar) getNetDevice(name string) (netDevice, error) {
+	return readNetDevice(ProcRoot, name)
+}
+
+func (c *concreteSigar) getSockets(protocol string) ([]socket, error) {
+	return readSockets(ProcRoot, protocol)
+}
+
+func (c *concreteSigar) getProcSockets(pid int) ([]uint64, error) {
+	return readProcSockets(ProcRoot, pid)
+}
+
+func (c *concreteSigar) getDiskStats(name string) (diskStats, error) {
+	return readDiskStats(ProcRoot, name)
+}
+
+func (c *concreteSigar) getMeminfo() (map[string]uint64, error) {
+	return readMeminfo(ProcRoot)
+}
+
+func (c *concreteSigar) now() time.Time {
+	return time.Now()
+}
+
 // AddAlarm adds an alarm to the pool and starts it immediately
 func AddAlarm(a *Alarm) error {
 	if a.Err == nil {
@@ -135,18 +271,24 @@ func AddAlarm(a *Alarm) error {
 	return a.Err
 }
 
-func compare(value1, value2 float64, c comparison) bool {
+func compare(v float64, target value, c comparison) bool {
 	switch c {
 	case above:
-		return value1 > value2
+		return v > target.value
 	case aboveEqual:
-		return value1 >= value2
+		return v >= target.value
 	case equal:
-		return value1 == value2
+		return math.Abs(v-target.value) <= target.tolerance
+	case notEqual:
+		return math.Abs(v-target.value) > target.tolerance
 	case below:
-		return value1 < value2
+		return v < target.value
 	case belowEqual:
-		return value1 <= value2
+		return v <= target.value
+	case between:
+		return v >= target.value && v <= target.upper
+	case outside:
+		return v < target.value || v > target.upper
 	}
 	return false
 }
@@ -154,6 +296,9 @@ func compare(value1, value2 float64, c comparison) bool {
 // SetMetricsManager allows to set a specific sigar manager
 func (j *Alarm) SetMetricsManager(m sigarMetrics) {
 	(*j).metricsManager = m
+	for _, c := range j.children {
+		c.SetMetricsManager(m)
+	}
 }
 
 func pidExists(pid int) bool {
@@ -163,87 +308,179 @@ func pidExists(pid int) bool {
 
 func check(Alarm *Alarm) {
 	if Alarm.Err == nil {
-		switch Alarm.jobType {
-		case loadAlarm:
-			Alarm.result <- compare(
-				getLoadAverage(
-					Alarm.stats.period,
-					Alarm.metricsManager,
-					Alarm.value.percentage),
-				Alarm.value.value,
-				Alarm.comparison)
-
-		case uptimeAlarm:
-			Alarm.result <- compare(
-				getUptime(
-					Alarm.metricsManager),
-				Alarm.value.value,
-				Alarm.comparison)
-
-		case procAlarm:
-			switch Alarm.stats.metric {
-			case usedMetric:
-				Alarm.result <- compare(
-					getPidMemory(Alarm.stats.proc.pid,
-						Alarm.metricsManager,
-						Alarm.value.percentage),
-					Alarm.value.value,
-					Alarm.comparison)
-			case timeMetric:
-				Alarm.result <- compare(
-					getPidTime(Alarm.stats.proc.pid,
-						Alarm.metricsManager),
-					Alarm.value.value,
-					Alarm.comparison)
-			case statusMetric:
-				Alarm.result <- compare(
-					float64(getPidState(Alarm.stats.proc.pid,
-						Alarm.metricsManager)),
-					float64(Alarm.stats.proc.state),
-					equal)
-			}
+		Alarm.result <- evaluate(Alarm)
+	}
+}
 
-		case memoryAlarm:
-			switch Alarm.stats.metric {
-			case freeMetric:
-				Alarm.result <- compare(
-					float64(getActualFreeMemory(
-						Alarm.metricsManager,
-						Alarm.value.percentage,
-					)),
-					Alarm.value.value,
-					Alarm.comparison)
-			case usedMetric:
-				Alarm.result <- compare(
-					float64(getActualUsedMemory(
-						Alarm.metricsManager,
-						Alarm.value.percentage,
-					)),
-					Alarm.value.value,
-					Alarm.comparison)
-			}
+func evaluate(a *Alarm) bool {
+	if a.jobType == compositeAlarm {
+		return combine(a)
+	}
 
-		case swapAlarm:
-			switch Alarm.stats.metric {
-			case freeMetric:
-				Alarm.result <- compare(
-					float64(getActualFreeSwap(
-						Alarm.metricsManager,
-						Alarm.value.percentage,
-					)),
-					Alarm.value.value,
-					Alarm.comparison)
-			case usedMetric:
-				Alarm.result <- compare(
-					float64(getActualUsedSwap(
-						Alarm.metricsManager,
-						Alarm.value.percentage,
-					)),
-					Alarm.value.value,
-					Alarm.comparison)
-			}
+	if isStatus(a) {
+		switch a.stats.metric {
+		case restartedMetric:
+			return restarted(a)
+		case exitedMetric:
+			return exited(a)
+		case listeningMetric:
+			bound, checked := listening(a)
+			return checked && bound
+		case notListeningMetric:
+			bound, checked := listening(a)
+			return checked && !bound
+		}
+		if !locate(a) {
+			return false
+		}
+		return matchState(&a.stats.proc,
+			state(getPidState(a.stats.proc.pid,
+				a.metricsManager)))
+	}
+
+	v, measured := measure(a)
+	if !measured {
+		return false
+	}
+
+	v, ready := transform(a, v)
+	if !ready {
+		return false
+	}
+	return compare(v, a.value, a.comparison)
+}
+
+// matchState checks the process state against the states set, or against the previous one when looking for changes
+func matchState(p *proc, s state) bool {
+	if p.changed {
+		fired := p.last != 0 && p.last != s
+		p.last = s
+		return fired
+	}
+	return inStates(p, s)
+}
+
+func inStates(p *proc, s state) bool {
+	for _, expected := range p.states {
+		if s == expected {
+			return !p.exclude
+		}
+	}
+	return p.exclude
+}
+
+// measureProc gets the current value of the alarm metric for the given process
+func measureProc(a *Alarm, pid uint) (float64, bool) {
+	switch a.stats.metric {
+	case usedMetric:
+		return getPidMemory(pid,
+			a.metricsManager,
+			a.value.percentage)
+	case timeMetric:
+		return getPidTime(pid,
+			a.metricsManager)
+	case cpuMetric:
+		return getPidCPU(a, pid)
+	case cpuTimeMetric:
+		return getPidCPUTime(pid,
+			a.metricsManager)
+	case filesMetric:
+		return getPidFiles(pid,
+			a.metricsManager,
+			a.value.percentage)
+	case threadsMetric:
+		return getPidThreads(pid,
+			a.metricsManager)
+	case majorFaultsMetric:
+		return getPidMajorFaults(a, pid)
+	case readMetric, writeMetric:
+		return getPidIO(a, pid)
+	}
+	return 0.0, false
+}
+
+// measure gets the current value of the alarm metric.
+// It returns false when the value can't be measured, like when no process is found
+func measure(a *Alarm) (float64, bool) {
+	defer forget(a, a.metricsManager.now())
+
+	switch a.jobType {
+	case expressionAlarm:
+		return calculate(a)
+
+	case loadAlarm:
+		return getLoadAverage(
+			a.stats.period,
+			a.stats.metric,
+			a.metricsManager,
+			a.value.percentage), true
+
+	case uptimeAlarm:
+		return getUptime(
+			a.metricsManager), true
+
+	case processesAlarm:
+		return getProcesses(
+			a.stats.metric,
+			a.metricsManager,
+			a.value.percentage)
+
+	case networkAlarm:
+		return getNetwork(a)
+
+	case diskAlarm:
+		return getDisk(a)
+
+	case tcpAlarm:
+		return getTCP(
+			a.stats.metric,
+			a.stats.port,
+			a.metricsManager,
+			a.value.percentage)
+
+	case procAlarm:
+		if isGroup(a) {
+			return aggregate(a)
+		}
+		if !locate(a) {
+			return 0.0, false
+		}
+		return measureProc(a, a.stats.proc.pid)
+
+	case memoryAlarm:
+		switch a.stats.metric {
+		case totalMetric:
+			return getTotalMemory(a.metricsManager) / 1048576, true
+		case freeMetric:
+			return getActualFreeMemory(
+				a.metricsManager,
+				a.value.percentage), true
+		case usedMetric:
+			return getActualUsedMemory(
+				a.metricsManager,
+				a.value.percentage), true
+		case fieldMetric:
+			return getMemoryField(
+				a.stats.field,
+				a.metricsManager,
+				a.value.percentage)
+		}
+
+	case swapAlarm:
+		switch a.stats.metric {
+		case totalMetric:
+			return getTotalSwap(a.metricsManager) / 1048576, true
+		case freeMetric:
+			return getActualFreeSwap(
+				a.metricsManager,
+				a.value.percentage), true
+		case usedMetric:
+			return getActualUsedSwap(
+				a.metricsManager,
+				a.value.percentage), true
 		}
 	}
+	return 0.0, false
 }
 
 // SystemLoad creates an alarm based on load metric
@@ -264,8 +501,25 @@ func SystemLoad(p period) *Alarm {
 	return a
 }
 
-// SystemProc creates an alarm based on a process specified by PID
-func SystemProc(pid uint) *Alarm {
+// SystemUser creates an alarm based on all the processes owned by the user, adding up their metrics unless another aggregation is used.
+// When counting them, a percentage is calculated against the limit of processes of the user using their threads,
+// as the kernel applies that limit to every thread of the user
+func SystemUser(name string) *Alarm {
+	a := newProc(proc{
+		selector: byUser,
+		name:     name,
+	})
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		a.Err = ErrInexistentUser
+		return a
+	}
+	a.stats.proc.uid, _ = strconv.Atoi(u.Uid)
+	return a
+}
+
+func newProc(p proc) *Alarm {
 	a := &Alarm{
 		jobType: procAlarm,
 		value: value{
@@ -275,15 +529,62 @@ func SystemProc(pid uint) *Alarm {
 		stats: stats{
 			metric: 0,
 			period: 0,
-			proc: proc{
-				pid:   pid,
-				state: Unknown,
-			}},
+			proc:   p,
+		},
 	}
+	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// SystemProc creates an alarm based on a process specified by PID
+func SystemProc(pid uint) *Alarm {
+	a := newProc(proc{
+		pid: pid,
+	})
 	if !pidExists(int(pid)) {
 		a.Err = ErrInexistentPid
 	}
-	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// SystemProcByName creates an alarm based on the process with the given name.
+// The process is looked up again on every check, so restarted processes keep being monitored.
+// The name is the one of the executable reported by the kernel, which only keeps the first 15 characters of it
+// and is not changed by processes rewriting their command line, like nginx workers
+func SystemProcByName(name string) *Alarm {
+	a := newProc(proc{
+		selector: byName,
+		name:     name,
+	})
+	if name == "" {
+		a.Err = ErrIncorrectSelector
+	}
+	return a
+}
+
+// SystemProcMatching creates an alarm based on the process whose command line matches the regular expression.
+// The process is looked up again on every check, so restarted processes keep being monitored
+func SystemProcMatching(pattern *regexp.Regexp) *Alarm {
+	a := newProc(proc{
+		selector: byPattern,
+		pattern:  pattern,
+	})
+	if pattern == nil {
+		a.Err = ErrIncorrectSelector
+	}
+	return a
+}
+
+// SystemProcFromPidfile creates an alarm based on the process whose PID is written in the given file.
+// The file is read again on every check, so restarted processes keep being monitored
+func SystemProcFromPidfile(path string) *Alarm {
+	a := newProc(proc{
+		selector: byPidfile,
+		pidfile:  path,
+	})
+	if path == "" {
+		a.Err = ErrIncorrectSelector
+	}
 	return a
 }
 
@@ -321,6 +622,109 @@ func SystemSwap() *Alarm {
 	return a
 }
 
+// SystemProcesses creates an alarm based on all the processes of the system
+func SystemProcesses() *Alarm {
+	a := &Alarm{
+		jobType: processesAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result: make(chan bool),
+		stats: stats{
+			metric: 0,
+			period: 0,
+		},
+	}
+	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// SystemNetwork creates an alarm based on the traffic of a network interface specified by name.
+// Nothing is measured while the interface doesn't exist, so it can be created after the alarm
+func SystemNetwork(name string) *Alarm {
+	a := &Alarm{
+		jobType: networkAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result: make(chan bool),
+		stats: stats{
+			metric: 0,
+			period: 0,
+			device: name,
+		},
+	}
+	a.SetMetricsManager(&concreteSigar{})
+
+	if name == "" {
+		a.Err = ErrInexistentInterface
+	}
+	return a
+}
+
+// SystemTCP creates an alarm based on the TCP sockets of the system, both IPv4 and IPv6
+func SystemTCP() *Alarm {
+	a := &Alarm{
+		jobType: tcpAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result: make(chan bool),
+		stats: stats{
+			metric: 0,
+			period: 0,
+		},
+	}
+	a.SetMetricsManager(&concreteSigar{})
+	return a
+}
+
+// SystemPort creates an alarm based on a local port, using TCP unless UDP is specified
+func SystemPort(port int) *Alarm {
+	a := &Alarm{
+		jobType: portAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result: make(chan bool),
+		stats: stats{
+			metric:   0,
+			period:   0,
+			port:     port,
+			protocol: "tcp",
+		},
+	}
+	a.SetMetricsManager(&concreteSigar{})
+
+	if port < 1 || port > 65535 {
+		a.Err = ErrIncorrectPort
+	}
+	return a
+}
+
+// SystemDisk creates an alarm based on the activity of a block device specified by name.
+// Nothing is measured while the device doesn't exist, so it can be attached after the alarm
+func SystemDisk(name string) *Alarm {
+	a := &Alarm{
+		jobType: diskAlarm,
+		value: value{
+			value:      notSet,
+			percentage: false},
+		result: make(chan bool),
+		stats: stats{
+			metric: 0,
+			period: 0,
+			device: name,
+		},
+	}
+	a.SetMetricsManager(&concreteSigar{})
+
+	if name == "" {
+		a.Err = ErrInexistentDisk
+	}
+	return a
+}
+
 // SystemUptime creates an alarm based on system uptime
 func SystemUptime() *Alarm {
 	a := &Alarm{
@@ -338,18 +742,84 @@ func SystemUptime() *Alarm {
 	return a
 }
 
+// isComplete checks that the alarm has everything needed for being evaluated
+func isComplete(a *Alarm) bool {
+	return a.comparison != comparisonNotDefined || isStatus(a) || a.jobType == compositeAlarm
+}
+
+// isMetricDefined checks that the alarm has a metric, when its type needs one for being measured
+func isMetricDefined(a *Alarm) bool {
+	switch a.jobType {
+	case memoryAlarm, swapAlarm, processesAlarm, networkAlarm, tcpAlarm, diskAlarm, portAlarm:
+		return a.stats.metric != 0
+	case procAlarm:
+		return a.stats.metric != 0 || a.stats.proc.aggregation == countAggregation
+	}
+	return true
+}
+
+// isPredictionComplete checks that a trend window is followed by a prediction setting its horizon
+func isPredictionComplete(a *Alarm) bool {
+	return a.modifier != predictModifier || a.horizon > 0
+}
+
+// isGroupStatusCorrect checks that the state of a group of processes is only used for counting them
+func isGroupStatusCorrect(a *Alarm) bool {
+	return a.stats.metric != statusMetric || !isGroup(a) || a.stats.proc.aggregation == countAggregation
+}
+
+// validate checks the whole chain of the alarm once it is built, as some of its parts can be used in any order
+func validate(a *Alarm) error {
+	switch {
+	case !isMetricDefined(a):
+		return ErrMetricNotDefined
+	case !isComplete(a):
+		return ErrComparisonNotDefined
+	case !isPredictionComplete(a):
+		return ErrIncorrectHorizon
+	case a.modifier != modifierNotDefined && isStatus(a):
+		return ErrIncorrectTypeForModifier
+	case !isGroupStatusCorrect(a):
+		return ErrIncorrectTypeForAggregation
+	}
+	return nil
+}
+
+// isStatus checks if the alarm is fired by the state of a process instead of comparing a value
+func isStatus(a *Alarm) bool {
+	switch a.stats.metric {
+	case restartedMetric, exitedMetric, listeningMetric, notListeningMetric:
+		return true
+	case statusMetric:
+		return a.stats.proc.aggregation != countAggregation
+	}
+	return false
+}
+
 func (j *Alarm) execute() {
 	if j.Err == nil {
-		j.task()
+		if j.task != nil {
+			j.task()
+		}
+		remediate(j)
 	}
 }
 
+// NoMatch allows a func to be specified.
+// This callback will be executed on every check where no process is found for the alarm
+func (j *Alarm) NoMatch(f func()) *Alarm {
+	if j.Err == nil {
+		(*j).noMatch = f
+	}
+	return j
+}
+
 // Run allows a func to be specified.
 // This callback will be executed when the alarm is fired
 func (j *Alarm) Run(f func()) *Alarm {
 	if j.Err == nil {
-		if (j.comparison == comparisonNotDefined) && j.stats.metric != statusMetric {
-			(*j).Err = ErrComparisonNotDefined
+		if err := validate(j); err != nil {
+			(*j).Err = err
 			return j
 		}
 		(*j).task = f
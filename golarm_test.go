@@ -1,10 +1,16 @@
 package golarm
 
 import (
+	"bufio"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"regexp"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/cloudfoundry/gosigar"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -189,6 +195,22 @@ func TestSystemLoad(test *testing.T) {
 	go check(a)
 	assert.Equal(test, <-a.result, false)
 	assert.Equal(test, a.Err, nil)
+
+	// load of 1 in 4 CPUs
+	a = SystemLoad(OneMinPeriod).Equal(25).Percent().Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.Err, nil)
+
+	a = SystemLoad(OneMinPeriod).PerCore().Equal(0.25).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.Err, nil)
+
+	a = SystemMemory().PerCore().Above(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
 }
 
 func TestSystemMemory(test *testing.T) {
@@ -339,3 +361,1409 @@ func TestRealSystemUptime(test *testing.T) {
 	assert.Nil(test, a.Err, nil)
 	assert.Nil(test, err, nil)
 }
+
+func TestIncorrectModifiers(test *testing.T) {
+	a := SystemProc(uint(os.Getpid())).Status(Zombie).Rate().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForModifier)
+
+	a = SystemMemory().Used().Rate().Delta(time.Minute).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrMultipleModifierDefined)
+
+	a = SystemMemory().Used().Delta(0).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectWindow)
+
+	// status metrics can't be modified, whatever the order
+	a = SystemProc(uint(os.Getpid())).AvgOver(time.Minute).Status(Zombie).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForModifier)
+
+	a = SystemPort(80).Rate().Listening().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForModifier)
+}
+
+func TestRate(test *testing.T) {
+	start := time.Now()
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+
+	a := SystemMemory().Used().Rate().Above(50).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	// 120MB in two minutes
+	f.leaked = 120 * 1048576
+	f.clock = func() time.Time { return start.Add(2 * time.Minute) }
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	// 30MB in one minute
+	f.leaked = 150 * 1048576
+	f.clock = func() time.Time { return start.Add(3 * time.Minute) }
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemMemory().Free().Rate().Below(-20).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	f.leaked = 180 * 1048576
+	f.clock = func() time.Time { return start.Add(4 * time.Minute) }
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestDelta(test *testing.T) {
+	start := time.Now()
+
+	f := &fakeSigar{}
+	a := SystemProc(uint(os.Getpid())).Used().Delta(10 * time.Minute).Above(200).Run(func() {})
+	a.SetMetricsManager(f)
+
+	for minute := 0; minute <= 10; minute++ {
+		f.leaked = uint64(minute) * 30 * 1048576
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		go check(a)
+		// the window is not covered until ten minutes have passed
+		assert.Equal(test, <-a.result, minute == 10)
+	}
+
+	// memory stops growing
+	fired := true
+	for minute := 11; minute <= 20; minute++ {
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		go check(a)
+		fired = <-a.result
+	}
+	assert.Equal(test, fired, false)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestWindowedAggregations(test *testing.T) {
+	start := time.Now()
+
+	a := SystemMemory().Used().PercentileOver(1.5, time.Minute).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectPercentile)
+
+	a = SystemMemory().Used().AvgOver(-time.Minute).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectWindow)
+
+	f := &fakeSigar{}
+	avg := SystemMemory().Used().AvgOver(5 * time.Minute).Above(40).Percent().Run(func() {})
+	min := SystemMemory().Used().MinOver(5 * time.Minute).Above(25).Percent().Run(func() {})
+	max := SystemMemory().Used().MaxOver(5 * time.Minute).Above(40).Percent().Run(func() {})
+	p95 := SystemMemory().Used().PercentileOver(0.95, 5*time.Minute).Above(70).Percent().Run(func() {})
+
+	// used memory moves between 20% and 80% for 4 minutes, then stays at 30%.
+	// Nothing fires until the samples cover the whole window
+	used := []uint64{20, 80, 20, 80, 30, 30, 30, 30, 30, 30}
+	expected := map[*Alarm][]bool{
+		avg: {false, false, false, false, false, true, false, false, false, false},
+		min: {false, false, false, false, false, false, false, true, true, true},
+		max: {false, false, false, false, false, true, true, true, false, false},
+		p95: {false, false, false, false, false, true, true, true, false, false},
+	}
+
+	for minute, percent := range used {
+		f.leaked = percent*1000000 - 20000000
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		for a, results := range expected {
+			a.SetMetricsManager(f)
+			go check(a)
+			assert.Equal(test, <-a.result, results[minute])
+			assert.Nil(test, a.Err, nil)
+		}
+	}
+}
+
+func TestPrediction(test *testing.T) {
+	start := time.Now()
+
+	a := SystemMemory().Free().PredictBelow(0, 0).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectHorizon)
+
+	a = SystemMemory().Free().AvgOver(time.Minute).PredictBelow(0, time.Hour).Run(func() {})
+	assert.Equal(test, a.Err, ErrMultipleModifierDefined)
+
+	a = SystemMemory().Free().Above(5).PredictBelow(0, time.Hour).Run(func() {})
+	assert.Equal(test, a.Err, ErrMultipleComparisonDefined)
+
+	a = SystemMemory().Used().TrendOver(5 * time.Minute).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectHorizon)
+
+	f := &fakeSigar{}
+	// free memory starts at 76MB and decreases 1MB per minute
+	a = SystemMemory().Free().PredictBelow(10, time.Hour).Run(func() {})
+	a.SetMetricsManager(f)
+	b := SystemMemory().Free().TrendOver(5*time.Minute).PredictBelow(20, time.Hour).Run(func() {})
+	b.SetMetricsManager(f)
+
+	for minute := 0; minute <= 10; minute++ {
+		f.leaked = uint64(minute) * 1048576
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		go check(a)
+		go check(b)
+		// forecast reaches 10MB after 67 minutes
+		assert.Equal(test, <-a.result, minute >= 7)
+		assert.Equal(test, <-b.result, minute >= 1)
+	}
+
+	// memory stops decreasing and the short trend becomes flat
+	for minute := 11; minute <= 20; minute++ {
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		go check(b)
+		assert.Equal(test, <-b.result, minute < 12)
+	}
+	assert.Nil(test, a.Err, nil)
+	assert.Nil(test, b.Err, nil)
+}
+
+func TestAnomaly(test *testing.T) {
+	start := time.Now()
+
+	a := SystemLoad(OneMinPeriod).Anomalous(0, time.Hour).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectDeviation)
+
+	a = SystemMemory().Used().Anomalous(3, time.Hour).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPercentage)
+
+	a = SystemLoad(OneMinPeriod).Above(1).Anomalous(3, time.Hour).Run(func() {})
+	assert.Equal(test, a.Err, ErrMultipleComparisonDefined)
+
+	f := &fakeSigar{}
+	a = SystemMemory().Used().Anomalous(3, 10*time.Minute).Run(func() {})
+	a.SetMetricsManager(f)
+
+	// used memory moves 1MB up and down, spiking during the warm up
+	for minute := 0; minute <= 14; minute++ {
+		f.leaked = uint64(minute%2) * 1048576
+		if minute == 3 || minute == 14 {
+			f.leaked = 10 * 1048576
+		}
+		f.clock = func() time.Time { return start.Add(time.Duration(minute) * time.Minute) }
+		go check(a)
+		assert.Equal(test, <-a.result, minute == 14)
+	}
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestComposite(test *testing.T) {
+	a := All().Run(func() {})
+	assert.Equal(test, a.Err, ErrEmptyComposite)
+
+	a = All(SystemLoad(OneMinPeriod).Above(1), SystemMemory().Free()).Run(func() {})
+	assert.Equal(test, a.Err, ErrComparisonNotDefined)
+
+	a = Any(SystemLoad(OneMinPeriod).Above(1), SystemLoad(OneMinPeriod).Free()).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = Not(SystemLoad(OneMinPeriod).Above(1)).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForComparison)
+
+	highLoad := func() *Alarm { return SystemLoad(OneMinPeriod).AboveEqual(1) }
+	lowMemory := func() *Alarm { return SystemMemory().Free().Below(500) }
+	lowSwap := func() *Alarm { return SystemSwap().Free().Below(10) }
+
+	a = All(highLoad(), lowMemory()).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = All(highLoad(), lowMemory(), lowSwap()).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = Any(lowSwap(), lowMemory()).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = Any(lowSwap(), Not(highLoad())).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = All(Not(lowSwap()), SystemProc(uint(os.Getpid())).Status(Running)).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestExpression(test *testing.T) {
+	a := SystemSwap().Used().Add(SystemMemory().Used().Above(5)).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectOperand)
+
+	a = SystemSwap().Add(SystemMemory().Used()).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectOperand)
+
+	a = SystemSwap().Used().Add(SystemLoad(OneMinPeriod).Free()).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemSwap().Used().Add(SystemMemory().Used()).Above(5).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPercentage)
+
+	// (20MB + 20MB) / 100MB
+	a = SystemSwap().Used().Add(SystemMemory().Used()).Div(SystemMemory().Total()).Above(0.39).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemSwap().Used().Add(SystemMemory().Used()).Div(SystemMemory().Total()).Above(0.41).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	pid := uint(os.Getpid())
+	a = SystemProc(pid).Used().Add(SystemProc(pid).Used()).Equal(190).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemLoad(OneMinPeriod).Mul(SystemUptime()).Div(SystemMemory().Free().Sub(SystemMemory().Free())).Equal(0).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestRanges(test *testing.T) {
+	a := SystemMemory().Free().Between(50, 10).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectRange)
+
+	a = SystemMemory().Free().Outside(50, 110).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectValuesWithPercentage)
+
+	a = SystemMemory().Free().Above(5).Within(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForTolerance)
+
+	a = SystemMemory().Free().Equal(5).Within(-1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTolerance)
+
+	a = SystemProc(uint(os.Getpid())).Status(Running).Between(1, 5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForComparison)
+
+	a = SystemMemory().Free().Between(70, 80).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemMemory().Free().Between(70, 80).Percent().Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Free().Outside(70, 80).Percent().Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemMemory().Free().Outside(10, 20).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Free().Equal(76).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemMemory().Free().Equal(76).Within(0.5).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Free().NotEqual(76).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Free().NotEqual(76).Within(0.5).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestStatusSets(test *testing.T) {
+	pid := uint(os.Getpid())
+	a := SystemProc(pid).StatusIn().Run(func() {})
+	assert.Equal(test, a.Err, ErrEmptyStatus)
+
+	a = SystemProc(pid).StatusChanged().Above(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForComparison)
+
+	f := &fakeSigar{}
+	a = SystemProc(pid).StatusIn(Zombie, Stopped).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	f.state = 'Z'
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProc(pid).StatusNot(Running, Sleeping).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	f.state = 'S'
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	for code, s := range map[sigar.RunState]state{'I': IdleThread, 'W': Paging, 'X': Dead, 't': TracingStop, 'P': Parked, 'D': Idle, '?': Unknown} {
+		f.state = code
+		a = SystemProc(pid).Status(s).Run(func() {})
+		a.SetMetricsManager(f)
+		go check(a)
+		assert.Equal(test, <-a.result, true)
+	}
+}
+
+func TestStatusChanged(test *testing.T) {
+	f := &fakeSigar{}
+	a := SystemProc(uint(os.Getpid())).StatusChanged().Run(func() {})
+	a.SetMetricsManager(f)
+
+	for _, step := range []struct {
+		state sigar.RunState
+		fired bool
+	}{{'R', false}, {'R', false}, {'S', true}, {'S', false}, {'Z', true}} {
+		f.state = step.state
+		go check(a)
+		assert.Equal(test, <-a.result, step.fired)
+	}
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcSelectors(test *testing.T) {
+	a := SystemProcByName("").Used().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectSelector)
+
+	a = SystemProcMatching(nil).Used().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectSelector)
+
+	f := &fakeSigar{procs: map[int][]string{
+		300: {"/usr/sbin/nginx", "-g", "daemon off;"},
+		301: {"nginx: worker process"},
+		400: {"/usr/bin/php-fpm", "--nodaemonize"},
+		500: {"/usr/lib/systemd/systemd-journald"},
+	}}
+
+	// the kernel truncates the names longer than 15 characters
+	a = SystemProcByName("systemd-journald").Status(Running).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.stats.proc.pid, uint(500))
+
+	a = SystemProcByName("nginx").Status(Running).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.stats.proc.pid, uint(300))
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemProcMatching(regexp.MustCompile(`php-fpm .*--nodaemonize`)).Used().Equal(95).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.stats.proc.pid, uint(400))
+
+	missing := 0
+	a = SystemProcByName("php-fpm").Used().Equal(95).NoMatch(func() { missing++ }).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, missing, 0)
+
+	// the process is restarted with a different pid
+	delete(f.procs, 400)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Equal(test, missing, 1)
+
+	f.procs[500] = []string{"/usr/bin/php-fpm"}
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.stats.proc.pid, uint(500))
+	assert.Equal(test, missing, 1)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcFromPidfile(test *testing.T) {
+	pidfile, err := ioutil.TempFile("", "golarm")
+	assert.Nil(test, err)
+	defer os.Remove(pidfile.Name())
+	pidfile.WriteString("301\n")
+	pidfile.Close()
+
+	f := &fakeSigar{procs: map[int][]string{
+		301: {"nginx: worker process"},
+	}}
+	missing := false
+	a := SystemProcFromPidfile(pidfile.Name()).Status(Running).NoMatch(func() { missing = true }).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, a.stats.proc.pid, uint(301))
+	assert.Equal(test, missing, false)
+
+	ioutil.WriteFile(pidfile.Name(), []byte("302"), 0644)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Equal(test, missing, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcAggregations(test *testing.T) {
+	a := SystemMemory().Used().Sum().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("php-fpm").Status(Zombie).Max().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("php-fpm").Used().Sum().Count().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrMultipleAggregationDefined)
+
+	a = SystemProcByName("php-fpm").Count().Above(5).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPercentage)
+
+	f := &fakeSigar{procs: map[int][]string{
+		300: {"/usr/sbin/nginx"},
+		401: {"php-fpm"},
+		402: {"php-fpm"},
+		403: {"php-fpm"},
+	}}
+
+	a = SystemProcByName("php-fpm").Used().Sum().Equal(285).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemProcByName("php-fpm").Used().Max().Equal(95).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("php-fpm").Count().AboveEqual(3).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("php-fpm").StatusIn(Zombie).Count().Above(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	f.state = 'Z'
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	missing := false
+	// the process with the PID is not running anymore
+	a = SystemProc(uint(os.Getpid())).Count().Equal(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("apache2").Count().Equal(0).NoMatch(func() { missing = true }).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, missing, false)
+
+	a = SystemProcByName("apache2").Used().Sum().Below(10).NoMatch(func() { missing = true }).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Equal(test, missing, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcCPU(test *testing.T) {
+	start := time.Now()
+
+	a := SystemMemory().CPU().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+	core := SystemProc(uint(os.Getpid())).CPU().Above(40).Run(func() {})
+	core.SetMetricsManager(f)
+	cores := SystemProc(uint(os.Getpid())).CPU().Above(40).Percent().Run(func() {})
+	cores.SetMetricsManager(f)
+
+	go check(core)
+	assert.Equal(test, <-core.result, false)
+	go check(cores)
+	assert.Equal(test, <-cores.result, false)
+
+	// one second of CPU during the last two seconds, 50% of a core and 12.5% of all of them
+	f.cpu = 1000
+	f.clock = func() time.Time { return start.Add(2 * time.Second) }
+	go check(core)
+	assert.Equal(test, <-core.result, true)
+	go check(cores)
+	assert.Equal(test, <-cores.result, false)
+	assert.Nil(test, core.Err, nil)
+	assert.Nil(test, cores.Err, nil)
+
+	f = &fakeSigar{procs: map[int][]string{
+		401: {"php-fpm"},
+		402: {"php-fpm"},
+	}, clock: func() time.Time { return start.Add(2 * time.Second) }}
+	a = SystemProcByName("php-fpm").CPU().Sum().Equal(100).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	// a new worker appears, which is not counted until the next check
+	f.procs[403] = []string{"php-fpm"}
+	f.cpu = 2000
+	f.clock = func() time.Time { return start.Add(6 * time.Second) }
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Equal(test, len(a.counters), 3)
+
+	// finished workers are forgotten
+	delete(f.procs, 401)
+	delete(f.procs, 402)
+	f.clock = func() time.Time { return start.Add(8 * time.Second) }
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Equal(test, len(a.counters), 1)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcTimes(test *testing.T) {
+	start := time.Now()
+
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+	a := SystemProc(uint(os.Getpid())).RunningTime().Equal(120).Within(0.01).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	// keep the start time fixed while the clock moves 30 minutes forward
+	startTime, _ := f.getProcTime(0)
+	f.procStart = startTime.StartTime
+	f.clock = func() time.Time { return start.Add(30 * time.Minute) }
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemProc(uint(os.Getpid())).RunningTime().Above(149).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	// 123456ms of CPU
+	a = SystemProc(uint(os.Getpid())).CPUTime().Between(2, 2.1).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	f.cpu = 60000
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	// nothing is measured once the process is gone
+	f.procs = map[int][]string{1: {"init"}}
+	for _, a := range []*Alarm{
+		SystemProc(uint(os.Getpid())).RunningTime().Below(5).Run(func() {}),
+		SystemProc(uint(os.Getpid())).CPUTime().Below(5).Run(func() {}),
+		SystemProc(uint(os.Getpid())).Used().Below(5).Run(func() {}),
+	} {
+		a.SetMetricsManager(f)
+		go check(a)
+		assert.Equal(test, <-a.result, false)
+		assert.Nil(test, a.Err, nil)
+	}
+}
+
+func TestProcFilesAndThreads(test *testing.T) {
+	a := SystemSwap().FileDescriptors().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemProc(uint(os.Getpid())).FileDescriptors().Equal(6).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	// 6 of 8 files
+	a = SystemProc(uint(os.Getpid())).FileDescriptors().Above(70).Percent().Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProc(uint(os.Getpid())).FileDescriptors().Above(80).Percent().Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemProc(uint(os.Getpid())).Threads().Above(10).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestRealProcFiles(test *testing.T) {
+	a := SystemProc(uint(os.Getpid())).FileDescriptors().Above(0).Run(func() {})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProc(uint(os.Getpid())).Threads().Above(0).Run(func() {})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcFaultsAndIO(test *testing.T) {
+	start := time.Now()
+
+	a := SystemMemory().ReadBytes().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+	faults := SystemProc(uint(os.Getpid())).MajorFaults().Above(50).Run(func() {})
+	read := SystemProc(uint(os.Getpid())).ReadBytes().AboveEqual(2).Run(func() {})
+	written := SystemProc(uint(os.Getpid())).WrittenBytes().Below(1).Run(func() {})
+	alarms := []*Alarm{faults, read, written}
+
+	for _, a := range alarms {
+		a.SetMetricsManager(f)
+		go check(a)
+		assert.Equal(test, <-a.result, false)
+	}
+
+	// 1000 faults and 20MB during 10 seconds
+	f.faults = 1000
+	f.io = 20 * 1048576
+	f.clock = func() time.Time { return start.Add(10 * time.Second) }
+	for _, a := range alarms {
+		go check(a)
+		assert.Equal(test, <-a.result, a != written)
+		assert.Nil(test, a.Err, nil)
+	}
+}
+
+func TestProcRestarted(test *testing.T) {
+	a := SystemProcByName("nginx").Count().Restarted().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("nginx").Restarted().Above(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForComparison)
+
+	f := &fakeSigar{procs: map[int][]string{
+		300: {"nginx"},
+	}, procStart: 1000}
+	a = SystemProcByName("nginx").Restarted().Run(func() {})
+	a.SetMetricsManager(f)
+
+	for _, step := range []struct {
+		pid   int
+		start uint64
+		fired bool
+	}{{300, 1000, false}, {300, 1000, false}, {301, 2000, true}, {301, 2000, false}, {0, 0, false}, {302, 3000, true}, {302, 4000, true}} {
+		f.procs = map[int][]string{}
+		if step.pid != 0 {
+			f.procs[step.pid] = []string{"nginx"}
+		}
+		f.procStart = step.start
+		go check(a)
+		assert.Equal(test, <-a.result, step.fired)
+	}
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcExited(test *testing.T) {
+	f := &fakeSigar{procs: map[int][]string{
+		300: {"nginx"},
+	}}
+	a := SystemProcByName("nginx").Exited().Run(func() {})
+	a.SetMetricsManager(f)
+
+	for _, step := range []struct {
+		pid   int
+		fired bool
+	}{{300, false}, {300, false}, {0, true}, {0, false}, {301, false}, {302, true}} {
+		f.procs = map[int][]string{}
+		if step.pid != 0 {
+			f.procs[step.pid] = []string{"nginx"}
+		}
+		go check(a)
+		assert.Equal(test, <-a.result, step.fired)
+	}
+	assert.Nil(test, a.Err, nil)
+
+	f.procs = map[int][]string{os.Getpid(): {"golarm.test"}}
+	a = SystemProc(uint(os.Getpid())).Exited().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	delete(f.procs, os.Getpid())
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestIncorrectActions(test *testing.T) {
+	a := SystemMemory().Used().Above(90).Signal(syscall.SIGHUP)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	a = SystemProcByName("php-fpm").Used().Sum().Above(90).Kill(time.Second)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	a = SystemProc(uint(os.Getpid())).Used().Kill(time.Second)
+	assert.Equal(test, a.Err, ErrComparisonNotDefined)
+
+	a = SystemProc(uint(os.Getpid())).Used().Above(90).Throttle(0)
+	assert.Equal(test, a.Err, ErrIncorrectInterval)
+
+	a = SystemProc(uint(os.Getpid())).Used().Above(90).Kill(0)
+	assert.Equal(test, a.Err, ErrIncorrectGrace)
+
+	// the process is gone, and its PID may belong to another one
+	a = SystemProc(uint(os.Getpid())).Exited().Signal(syscall.SIGHUP)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	a = SystemProc(uint(os.Getpid())).Exited().Kill(time.Second)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	a = SystemProc(uint(os.Getpid())).Exited().Restart("true")
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestActionErrors(test *testing.T) {
+	var failure error
+	a := SystemProc(uint(os.Getpid())).Used().Above(0).Restart("/inexistent/command").OnActionError(func(err error) {
+		failure = err
+	})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	a.execute()
+	assert.NotNil(test, failure)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSameProcess(test *testing.T) {
+	f := &fakeSigar{procStart: 1000}
+	assert.Equal(test, sameProcess(f, os.Getpid(), 1000), true)
+
+	// the PID was given to a process started later
+	f.procStart = 2000
+	assert.Equal(test, sameProcess(f, os.Getpid(), 1000), false)
+
+	f.procs = map[int][]string{1: {"init"}}
+	assert.Equal(test, sameProcess(f, os.Getpid(), 2000), false)
+}
+
+func TestActionsThrottling(test *testing.T) {
+	start := time.Now()
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+
+	acted := make([]int, 0)
+	a := SystemProc(uint(os.Getpid())).Exited().Throttle(10 * time.Minute)
+	a.SetMetricsManager(f)
+	a.actions = append(a.actions, func(pid int) error {
+		acted = append(acted, pid)
+		return nil
+	})
+
+	a.execute()
+	f.clock = func() time.Time { return start.Add(9 * time.Minute) }
+	a.execute()
+	f.clock = func() time.Time { return start.Add(10 * time.Minute) }
+	a.execute()
+	assert.Equal(test, acted, []int{os.Getpid(), os.Getpid()})
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestKillAction(test *testing.T) {
+	// the shell ignores SIGTERM, so it is only killed after the grace period
+	cmd := exec.Command("sh", "-c", "trap '' TERM; echo ready; while true; do sleep 1; done")
+	stdout, _ := cmd.StdoutPipe()
+	assert.Nil(test, cmd.Start())
+	bufio.NewReader(stdout).ReadString('\n')
+
+	a := SystemProc(uint(cmd.Process.Pid)).Used().Above(0).Kill(100 * time.Millisecond)
+	a.SetMetricsManager(&fakeSigar{procStart: 1000})
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	a.execute()
+
+	done := make(chan error)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+		status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		assert.Equal(test, status.Signal(), syscall.SIGKILL)
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		test.Fatal("process not killed")
+	}
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestProcWithChildren(test *testing.T) {
+	a := SystemMemory().WithChildren().Used().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("supervisord").WithChildren().Exited().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	// the state of the descendants can only be counted, whatever the order
+	a = SystemProcByName("supervisord").WithChildren().Status(Zombie).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("supervisord").Status(Zombie).WithChildren().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("supervisord").WithChildren().StatusChanged().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("supervisord").StatusChanged().WithChildren().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = All(SystemProcByName("supervisord").WithChildren().Status(Zombie)).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemProcByName("supervisord").WithChildren().Used().Above(5).Signal(syscall.SIGHUP)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	// supervisord forks two workers, and one of them forks another one
+	f := &fakeSigar{
+		procs: map[int][]string{
+			1:   {"init"},
+			100: {"supervisord"},
+			101: {"worker"},
+			102: {"worker"},
+			103: {"worker"},
+			200: {"worker"},
+		},
+		parents: map[int]int{1: 0, 100: 1, 101: 100, 102: 100, 103: 102, 200: 1},
+	}
+
+	a = SystemProcByName("supervisord").WithChildren().Used().Equal(380).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemProcByName("supervisord").WithChildren().Count().Equal(4).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("supervisord").Status(Running).WithChildren().Count().Equal(4).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemProcByName("supervisord").WithChildren().Status(Zombie).Count().Equal(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemProcByName("supervisord").WithChildren().Used().Max().Equal(95).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("worker").WithChildren().Count().Equal(4).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcByName("supervisord").Used().Equal(95).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSystemUser(test *testing.T) {
+	start := time.Now()
+
+	a := SystemUser("golarm-inexistent-user").Count().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrInexistentUser)
+
+	a = SystemUser("root").Restarted().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemUser("root").Count().Above(500).Kill(time.Second)
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAction)
+
+	// the state of the processes of a user can only be counted
+	a = SystemUser("root").Status(Zombie).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemUser("root").StatusIn(Zombie, Dead).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemUser("root").StatusChanged().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemUser("root").StatusChanged().Count().Above(0).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	a = SystemUser("root").Count().StatusChanged().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForAggregation)
+
+	f := &fakeSigar{
+		procs: map[int][]string{
+			100: {"bash"},
+			101: {"php"},
+			102: {"php"},
+			200: {"nginx"},
+		},
+		uids:  map[int]int{100: 0, 101: 0, 102: 0},
+		clock: func() time.Time { return start },
+	}
+
+	a = SystemUser("root").Count().Equal(3).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	// 3 processes with 12 threads each, of 64 allowed
+	a = SystemUser("root").Count().Between(56, 57).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemUser("root").Count().Above(60).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemUser("root").Used().Equal(285).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemUser("root").Status(Zombie).Count().Equal(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	f.state = 'Z'
+	a = SystemUser("root").Count().Status(Zombie).Equal(3).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+	f.state = 0
+
+	a = SystemUser("root").CPU().Above(100).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	// every process uses half a core
+	f.cpu = 1000
+	f.clock = func() time.Time { return start.Add(2 * time.Second) }
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSystemProcesses(test *testing.T) {
+	a := SystemProcesses().Blocked().Zombies().Above(5).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemMemory().Zombies().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemProcesses().Used().Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	f := &fakeSigar{procs: map[int][]string{
+		1:   {"init"},
+		100: {"bash"},
+		200: {"nginx"},
+		300: {"php"},
+	}}
+
+	a = SystemProcesses().Count().Equal(4).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	// 4 of 32768 PIDs
+	a = SystemProcesses().Count().Between(0.01, 0.02).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcesses().Zombies().Above(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	f.state = 'Z'
+	a = SystemProcesses().Zombies().Equal(100).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcesses().Running().Equal(3).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemProcesses().Blocked().Equal(50).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSystemNetwork(test *testing.T) {
+	a := SystemNetwork("eth0").ReceivedBytes().Above(100).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemNetwork("").ReceivedBytes().Above(100).Run(func() {})
+	assert.Equal(test, a.Err, ErrInexistentInterface)
+
+	// nothing is measured for an interface that doesn't exist
+	a = SystemNetwork("inexistent0").ReceivedBytes().Above(-1).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemNetwork("eth0").Used().Above(100).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemMemory().Drops().Above(100).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemNetwork("eth0").SentBytes().Above(50).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPercentage)
+
+	start := time.Now()
+
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+	received := SystemNetwork("eth0").ReceivedBytes().Equal(1).Run(func() {})
+	packets := SystemNetwork("eth0").SentPackets().Equal(1048576).Run(func() {})
+	failures := SystemNetwork("eth0").Errors().Equal(2097152).Run(func() {})
+	drops := SystemNetwork("eth0").Drops().Above(0).Run(func() {})
+	alarms := []*Alarm{received, packets, failures, drops}
+
+	for _, a := range alarms {
+		a.SetMetricsManager(f)
+		go check(a)
+		assert.Equal(test, <-a.result, false)
+	}
+
+	// 10MB, packets, errors and drops during 10 seconds
+	f.traffic = 10 * 1048576
+	f.clock = func() time.Time { return start.Add(10 * time.Second) }
+	for _, a := range alarms {
+		go check(a)
+		assert.Equal(test, <-a.result, true)
+		assert.Nil(test, a.Err, nil)
+	}
+
+	// nothing else during the next 10 seconds
+	f.clock = func() time.Time { return start.Add(20 * time.Second) }
+	go check(drops)
+	assert.Equal(test, <-drops.result, false)
+}
+
+func TestSystemTCP(test *testing.T) {
+	a := SystemTCP().CloseWait().Port(8080).Above(10).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemTCP().Zombies().Above(10).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemMemory().Used().Port(8080).Above(10).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPort)
+
+	a = SystemTCP().Established().Port(70000).Above(10).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectPort)
+
+	f := &fakeSigar{}
+	a = SystemTCP().Count().Equal(8).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemTCP().Established().Equal(2).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemTCP().TimeWait().Equal(1).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	// one of them on IPv6
+	a = SystemTCP().CloseWait().Port(8080).Equal(2).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemTCP().Listen().Port(3306).Equal(1).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemTCP().Listen().Port(22).Above(0).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	// 2 of the 6 sockets of the port 8080
+	a = SystemTCP().Listen().Port(8080).Between(33, 34).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSystemPort(test *testing.T) {
+	a := SystemPort(8080).Listening().Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemPort(0).Listening().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectPort)
+
+	a = SystemPort(8080).Listening().Above(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForComparison)
+
+	a = SystemPort(8080).Established().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemMemory().Listening().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemTCP().UDP().Count().Above(1).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPort)
+
+	a = SystemPort(8080).OwnedBy(uint(9999999)).Listening().Run(func() {})
+	assert.Equal(test, a.Err, ErrInexistentPid)
+
+	f := &fakeSigar{}
+	a = SystemPort(8080).Listening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemPort(22).NotListening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemPort(53).UDP().Listening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	// connected UDP sockets are not bound for receiving from anyone
+	a = SystemPort(54336).UDP().Listening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	// the socket of the port 3306 is in the fixture of the process, while the one of 8080 is not
+	a = SystemPort(3306).OwnedBy(uint(os.Getpid())).Listening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemPort(8080).OwnedBy(uint(os.Getpid())).NotListening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	// the sockets of the owner can't be read, so nothing is known about them
+	f.denied = true
+	a = SystemPort(8080).OwnedBy(uint(os.Getpid())).NotListening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+
+	a = SystemPort(3306).OwnedBy(uint(os.Getpid())).Listening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	f.denied = false
+
+	// the owner is gone
+	f.procs = map[int][]string{1: {"init"}}
+	a = SystemPort(3306).OwnedBy(uint(os.Getpid())).NotListening().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestSystemDisk(test *testing.T) {
+	a := SystemDisk("sda").Utilisation().Above(80).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemDisk("").Utilisation().Above(80).Run(func() {})
+	assert.Equal(test, a.Err, ErrInexistentDisk)
+
+	// nothing is measured for a device that doesn't exist
+	a = SystemDisk("sdz").Reads().Above(-1).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemDisk("sda").Free().Above(80).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemProc(uint(os.Getpid())).Await().Above(80).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	a = SystemDisk("sda").Reads().Above(80).Percent().Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForPercentage)
+
+	start := time.Now()
+
+	f := &fakeSigar{}
+	f.clock = func() time.Time { return start }
+	utilisation := SystemDisk("sda").Utilisation().Equal(50).Run(func() {})
+	reads := SystemDisk("sda").Reads().Equal(500).Run(func() {})
+	writes := SystemDisk("sda").Writes().Equal(500).Run(func() {})
+	read := SystemDisk("sda").ReadBytes().Between(0.24, 0.25).Run(func() {})
+	written := SystemDisk("sda").WrittenBytes().Between(0.24, 0.25).Run(func() {})
+	await := SystemDisk("sda").Await().Equal(1).Run(func() {})
+	alarms := []*Alarm{utilisation, reads, writes, read, written, await}
+
+	for _, a := range alarms {
+		a.SetMetricsManager(f)
+		go check(a)
+		assert.Equal(test, <-a.result, false)
+	}
+
+	// 5000 operations, sectors and milliseconds during 10 seconds
+	f.disk = 5000
+	f.clock = func() time.Time { return start.Add(10 * time.Second) }
+	for _, a := range alarms {
+		go check(a)
+		assert.Equal(test, <-a.result, true)
+		assert.Nil(test, a.Err, nil)
+	}
+
+	// idle during the next 10 seconds
+	f.clock = func() time.Time { return start.Add(20 * time.Second) }
+	go check(await)
+	assert.Equal(test, <-await.result, false)
+	go check(utilisation)
+	assert.Equal(test, <-utilisation.result, false)
+}
+
+func TestSystemMemoryFields(test *testing.T) {
+	a := SystemMemory().Field("Shmem").Above(100).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemMemory().Field("").Above(100).Run(func() {})
+	assert.Equal(test, a.Err, ErrInexistentField)
+
+	// nothing is measured for a field that doesn't exist
+	a = SystemMemory().Field("Inexistent").Above(-1).Run(func() {})
+	a.SetMetricsManager(&fakeSigar{})
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemSwap().Dirty().Above(100).Run(func() {})
+	assert.Equal(test, a.Err, ErrIncorrectTypeForMetric)
+
+	f := &fakeSigar{}
+	a = SystemMemory().Dirty().Equal(100).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemMemory().Cached().Equal(2048).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Available().Equal(50).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Committed().Equal(75).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().HugePagesFree().Equal(128).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().HugePagesFree().Equal(25).Percent().Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, true)
+
+	a = SystemMemory().Writeback().Below(1).Run(func() {})
+	a.SetMetricsManager(f)
+	go check(a)
+	assert.Equal(test, <-a.result, false)
+	assert.Nil(test, a.Err, nil)
+}
+
+func TestMetricNotDefined(test *testing.T) {
+	a := SystemMemory().Below(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrMetricNotDefined)
+
+	a = SystemSwap().Below(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrMetricNotDefined)
+
+	a = SystemProc(uint(os.Getpid())).Above(5).Run(func() {})
+	assert.Equal(test, a.Err, ErrMetricNotDefined)
+
+	a = All(SystemMemory().Above(5)).Run(func() {})
+	assert.Equal(test, a.Err, ErrMetricNotDefined)
+
+	a = SystemProc(uint(os.Getpid())).Count().Above(5).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+
+	a = SystemLoad(OneMinPeriod).Above(5).Run(func() {})
+	assert.Nil(test, a.Err, nil)
+}
@@ -0,0 +1,216 @@
+package golarm
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type modifier int
+
+const (
+	modifierNotDefined modifier = iota
+	rateModifier
+	deltaModifier
+	avgModifier
+	minModifier
+	maxModifier
+	percentileModifier
+	predictModifier
+	anomalyModifier
+)
+
+type sample struct {
+	value float64
+	time  time.Time
+}
+
+// counterRate returns how much the counter with the given key has increased per second since the previous check.
+// It returns false when there is no previous value yet
+func counterRate(a *Alarm, key string, v float64) (float64, bool) {
+	t := a.metricsManager.now()
+	if a.counters == nil {
+		a.counters = make(map[string]sample)
+	}
+
+	previous, found := a.counters[key]
+	a.counters[key] = sample{value: v, time: t}
+
+	elapsed := t.Sub(previous.time).Seconds()
+	if !found || elapsed <= 0 || v < previous.value {
+		return 0.0, false
+	}
+	return (v - previous.value) / elapsed, true
+}
+
+// forget discards the counters not updated since the given time, like the ones of finished processes
+func forget(a *Alarm, since time.Time) {
+	for key, s := range a.counters {
+		if s.time.Before(since) {
+			delete(a.counters, key)
+		}
+	}
+}
+
+// record stores a new sample, discarding the ones not needed anymore.
+// The newest sample older than the window is kept, so the whole window is always covered
+func record(a *Alarm, v float64) {
+	t := a.metricsManager.now()
+	a.history = append(a.history, sample{value: v, time: t})
+
+	for len(a.history) > 2 && !a.history[1].time.After(t.Add(-a.window)) {
+		a.history = a.history[1:]
+	}
+}
+
+// windowed returns the recorded samples taken inside the window
+func windowed(a *Alarm) []float64 {
+	latest := a.history[len(a.history)-1].time
+	values := make([]float64, 0, len(a.history))
+
+	for _, s := range a.history {
+		if s.time.After(latest.Add(-a.window)) {
+			values = append(values, s.value)
+		}
+	}
+	return values
+}
+
+func average(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func minimum(values []float64) float64 {
+	value := values[0]
+	for _, v := range values[1:] {
+		value = math.Min(value, v)
+	}
+	return value
+}
+
+func maximum(values []float64) float64 {
+	value := values[0]
+	for _, v := range values[1:] {
+		value = math.Max(value, v)
+	}
+	return value
+}
+
+// percentile using the nearest-rank method, p must be in (0, 1]
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[rank-1]
+}
+
+// forecast fits a linear trend to the samples inside the window using least squares
+// and returns the value it predicts once the horizon has passed
+func forecast(a *Alarm) (float64, bool) {
+	latest := a.history[len(a.history)-1].time
+	var n, sumX, sumY, sumXY, sumXX float64
+
+	for _, s := range a.history {
+		if s.time.After(latest.Add(-a.window)) {
+			x := s.time.Sub(latest).Minutes()
+			n++
+			sumX += x
+			sumY += s.value
+			sumXY += x * s.value
+			sumXX += x * x
+		}
+	}
+
+	variance := n*sumXX - sumX*sumX
+	if n < 2 || variance == 0 {
+		return 0.0, false
+	}
+	slope := (n*sumXY - sumX*sumY) / variance
+	intercept := (sumY - slope*sumX) / n
+	return intercept + slope*a.horizon.Minutes(), true
+}
+
+// deviation returns how many standard deviations the latest sample is away from the mean of the previous ones inside the window.
+// Nothing is returned while warming up, until the samples cover the whole window
+func deviation(a *Alarm) (float64, bool) {
+	latest := a.history[len(a.history)-1]
+	if latest.time.Sub(a.history[0].time) < a.window {
+		return 0.0, false
+	}
+
+	values := windowed(a)
+	baseline := values[:len(values)-1]
+	if len(baseline) == 0 {
+		return 0.0, false
+	}
+
+	mean := average(baseline)
+	variance := 0.0
+	for _, v := range baseline {
+		variance += (v - mean) * (v - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(baseline)))
+
+	if deviation == 0 {
+		if latest.value == mean {
+			return 0.0, true
+		}
+		return math.Inf(1), true
+	}
+	return math.Abs(latest.value-mean) / deviation, true
+}
+
+// aggregateWindow applies the aggregation modifier to the samples inside the window
+func aggregateWindow(a *Alarm) float64 {
+	values := windowed(a)
+	switch a.modifier {
+	case minModifier:
+		return minimum(values)
+	case maxModifier:
+		return maximum(values)
+	case percentileModifier:
+		return percentile(values, a.percentile)
+	}
+	return average(values)
+}
+
+// transform applies the alarm modifier to the measured value.
+// It returns false when there are not enough samples to calculate it yet
+func transform(a *Alarm, v float64) (float64, bool) {
+	if a.modifier == modifierNotDefined {
+		return v, true
+	}
+
+	record(a, v)
+	oldest := a.history[0]
+	latest := a.history[len(a.history)-1]
+	elapsed := latest.time.Sub(oldest.time)
+
+	switch a.modifier {
+	case rateModifier:
+		if elapsed <= 0 {
+			return 0.0, false
+		}
+		return (latest.value - oldest.value) / elapsed.Minutes(), true
+	case deltaModifier:
+		if elapsed < a.window {
+			return 0.0, false
+		}
+		return latest.value - oldest.value, true
+	case avgModifier, minModifier, maxModifier, percentileModifier:
+		// nothing is aggregated until the samples cover the whole window
+		if elapsed < a.window {
+			return 0.0, false
+		}
+		return aggregateWindow(a), true
+	case predictModifier:
+		return forecast(a)
+	case anomalyModifier:
+		return deviation(a)
+	}
+	return 0.0, false
+}
@@ -1,5 +1,13 @@
 package golarm
 
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type period int
 type metric int
 type state int
@@ -12,6 +20,12 @@ var (
 		"T": 3.0,
 		"Z": 4.0,
 		"D": 5.0,
+		"X": 7.0,
+		"x": 7.0,
+		"t": 8.0,
+		"W": 9.0,
+		"P": 10.0,
+		"I": 11.0,
 	}
 )
 
@@ -20,9 +34,43 @@ const (
 	usedMetric
 	timeMetric
 	statusMetric
+	totalMetric
+	cpuMetric
+	cpuTimeMetric
+	filesMetric
+	threadsMetric
+	majorFaultsMetric
+	readMetric
+	writeMetric
+	restartedMetric
+	exitedMetric
+	countMetric
+	zombiesMetric
+	runningMetric
+	blockedMetric
+	perCoreMetric
+	receivedBytesMetric
+	sentBytesMetric
+	receivedPacketsMetric
+	sentPacketsMetric
+	errorsMetric
+	dropsMetric
+	establishedMetric
+	timeWaitMetric
+	closeWaitMetric
+	listenMetric
+	listeningMetric
+	notListeningMetric
+	utilisationMetric
+	readsMetric
+	writesMetric
+	awaitMetric
+	fieldMetric
 )
 
-// Linux process states to be used with status alarms
+// Linux process states to be used with status alarms.
+// Idle stands for uninterruptible sleep (D), IdleThread for idle kernel threads (I)
+// and Paging for paging or waking processes (W) depending on the kernel version
 const (
 	Sleeping state = iota + 1
 	Running
@@ -30,6 +78,11 @@ const (
 	Zombie
 	Idle
 	Unknown
+	Dead
+	TracingStop
+	Paging
+	Parked
+	IdleThread
 )
 
 type load struct {
@@ -37,14 +90,31 @@ type load struct {
 }
 
 type proc struct {
-	state state
-	pid   uint
+	states      []state
+	exclude     bool
+	changed     bool
+	last        state
+	pid         uint
+	selector    selector
+	name        string
+	pattern     *regexp.Regexp
+	pidfile     string
+	aggregation aggregation
+	descendants bool
+	uid         int
+	started     uint64
+	startedPid  uint
+	watching    bool
 }
 
 type stats struct {
-	period period
-	proc   proc
-	metric metric
+	period   period
+	proc     proc
+	metric   metric
+	device   string
+	port     int
+	protocol string
+	field    string
 }
 
 // Load average can be calculated for the last one minute, five minutes and fifteen minutes respectively. Load average is an indication of whether the system resources (mainly the CPU) are adequately available for the processes (system load) that are running, runnable or in uninterruptible sleep states during the previous n minutes.
@@ -54,7 +124,9 @@ const (
 	FifteenMinPeriod
 )
 
-func getLoadAverage(p period, manager sigarMetrics, percentage bool) float64 {
+// get the load average of the given period.
+// Percentages and per core values are calculated against the number of online CPUs
+func getLoadAverage(p period, m metric, manager sigarMetrics, percentage bool) float64 {
 	average, err := manager.GetLoadAverage()
 	value := 0.0
 
@@ -71,8 +143,11 @@ func getLoadAverage(p period, manager sigarMetrics, percentage bool) float64 {
 		value = float64(average.Fifteen)
 	}
 
+	if percentage || m == perCoreMetric {
+		value /= getCpuCount(manager)
+	}
 	if percentage {
-		value *= 10
+		value *= 100
 	}
 	return value
 }
@@ -80,34 +155,309 @@ func getLoadAverage(p period, manager sigarMetrics, percentage bool) float64 {
 func getPidState(pid uint, manager sigarMetrics) float64 {
 	value, err := manager.getProcState(int(pid))
 	if err != nil {
-		return 6.0
+		return float64(Unknown)
 	}
-	return states[string(value.State)]
+	if s, ok := states[string(value.State)]; ok {
+		return s
+	}
+	return float64(Unknown)
 }
 
-func getPidMemory(pid uint, manager sigarMetrics, percentage bool) float64 {
+func getPidMemory(pid uint, manager sigarMetrics, percentage bool) (float64, bool) {
 	memory, err := manager.getProcMem(int(pid))
 
 	if err != nil {
-		return 0.0
+		return 0.0, false
 	}
 
 	value := float64(memory.Resident / 1048576)
 
 	if percentage {
-		return 100.0 * (value / (getTotalMemory(manager) / 1048576))
+		return 100.0 * (value / (getTotalMemory(manager) / 1048576)), true
 	}
-	return value
+	return value, true
 }
 
-// get running time for PID in minutes
-func getPidTime(pid uint, manager sigarMetrics) float64 {
+// get running time for PID in minutes, since it was started
+func getPidTime(pid uint, manager sigarMetrics) (float64, bool) {
 	value, err := manager.getProcTime(int(pid))
 
 	if err != nil {
-		return 0.0
+		return 0.0, false
+	}
+	started := time.Unix(0, int64(value.StartTime)*int64(time.Millisecond))
+	return manager.now().Sub(started).Minutes(), true
+}
+
+// get CPU time consumed by PID in minutes
+func getPidCPUTime(pid uint, manager sigarMetrics) (float64, bool) {
+	value, err := manager.getProcTime(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+	return float64(value.Total) / 60000, true
+}
+
+// get CPU usage for PID as percentage of a single core, or of all of them when using a percentage
+func getPidCPU(a *Alarm, pid uint) (float64, bool) {
+	value, err := a.metricsManager.getProcTime(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+
+	rate, ready := counterRate(a, "cpu:"+strconv.Itoa(int(pid)), float64(value.Total))
+	if !ready {
+		return 0.0, false
+	}
+
+	// milliseconds of CPU per second
+	usage := rate / 10
+	if a.value.percentage {
+		return usage / getCpuCount(a.metricsManager), true
+	}
+	return usage, true
+}
+
+// get open file descriptors for PID, or its percentage against the soft limit
+func getPidFiles(pid uint, manager sigarMetrics, percentage bool) (float64, bool) {
+	files, err := manager.getProcFiles(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+
+	if percentage {
+		if files.limit == 0 {
+			return 0.0, true
+		}
+		return 100.0 * float64(files.open) / float64(files.limit), true
+	}
+	return float64(files.open), true
+}
+
+func getPidThreads(pid uint, manager sigarMetrics) (float64, bool) {
+	status, err := manager.getProcStatus(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+	return float64(status.threads), true
+}
+
+// get major page faults per second for PID
+func getPidMajorFaults(a *Alarm, pid uint) (float64, bool) {
+	memory, err := a.metricsManager.getProcMem(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+	return counterRate(a, "faults:"+strconv.Itoa(int(pid)), float64(memory.MajorFaults))
+}
+
+// get megabytes per second read from or written to storage by PID
+func getPidIO(a *Alarm, pid uint) (float64, bool) {
+	io, err := a.metricsManager.getProcIO(int(pid))
+
+	if err != nil {
+		return 0.0, false
+	}
+
+	bytes := io.readBytes
+	if a.stats.metric == writeMetric {
+		bytes = io.writeBytes
+	}
+
+	rate, ready := counterRate(a, "io:"+strconv.Itoa(int(pid)), float64(bytes))
+	return rate / 1048576, ready
+}
+
+// get megabytes, packets, errors or drops per second of a network interface
+func getNetwork(a *Alarm) (float64, bool) {
+	device, err := a.metricsManager.getNetDevice(a.stats.device)
+
+	if err != nil {
+		return 0.0, false
+	}
+
+	counter := 0.0
+	switch a.stats.metric {
+	case receivedBytesMetric:
+		counter = float64(device.receivedBytes) / 1048576
+	case sentBytesMetric:
+		counter = float64(device.sentBytes) / 1048576
+	case receivedPacketsMetric:
+		counter = float64(device.receivedPackets)
+	case sentPacketsMetric:
+		counter = float64(device.sentPackets)
+	case errorsMetric:
+		counter = float64(device.receivedErrors + device.sentErrors)
+	case dropsMetric:
+		counter = float64(device.receivedDrops + device.sentDrops)
 	}
-	return float64(value.Total / 1000)
+	return counterRate(a, "net:"+a.stats.device, counter)
+}
+
+// get number of TCP sockets in the state of the metric, all of them for the count, filtered by local port when set.
+// Percentages are calculated against all the TCP sockets with that port
+func getTCP(m metric, port int, manager sigarMetrics, percentage bool) (float64, bool) {
+	sockets, err := manager.getSockets("tcp")
+	if err != nil {
+		return 0.0, false
+	}
+
+	states := map[metric]int{
+		establishedMetric: tcpEstablished,
+		timeWaitMetric:    tcpTimeWait,
+		closeWaitMetric:   tcpCloseWait,
+		listenMetric:      tcpListen,
+	}
+
+	value, total := 0.0, 0.0
+	for _, s := range sockets {
+		if port != 0 && s.port != port {
+			continue
+		}
+		total++
+		if m == countMetric || s.state == states[m] {
+			value++
+		}
+	}
+
+	if !percentage {
+		return value, true
+	}
+	if total == 0 {
+		return 0.0, true
+	}
+	return 100.0 * value / total, true
+}
+
+// listening checks if there is a socket bound to the port of the alarm, owned by its process when set.
+// UDP sockets are bound when they are not connected to a remote address.
+// It returns false as second value when it can't be checked, like when the sockets of the owner can't be read without root,
+// while an owner that doesn't exist anymore isn't listening
+func listening(a *Alarm) (bool, bool) {
+	sockets, err := a.metricsManager.getSockets(a.stats.protocol)
+	if err != nil {
+		return false, false
+	}
+
+	bound := tcpListen
+	if a.stats.protocol == "udp" {
+		bound = tcpClose
+	}
+
+	inodes := map[uint64]bool{}
+	for _, s := range sockets {
+		if s.port == a.stats.port && s.state == bound {
+			inodes[s.inode] = true
+		}
+	}
+
+	if a.stats.proc.pid == 0 {
+		return len(inodes) > 0, true
+	}
+
+	owned, err := a.metricsManager.getProcSockets(int(a.stats.proc.pid))
+	if err != nil {
+		return false, os.IsNotExist(err)
+	}
+	for _, inode := range owned {
+		if inodes[inode] {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// get utilisation percentage, operations or megabytes per second, or average milliseconds per operation of a disk
+func getDisk(a *Alarm) (float64, bool) {
+	disk, err := a.metricsManager.getDiskStats(a.stats.device)
+
+	if err != nil {
+		return 0.0, false
+	}
+
+	key := "disk:" + a.stats.device
+	switch a.stats.metric {
+	case utilisationMetric:
+		// milliseconds doing I/O per second
+		busy, ready := counterRate(a, key, float64(disk.ioTime))
+		return busy / 10, ready
+	case readsMetric:
+		return counterRate(a, key, float64(disk.reads))
+	case writesMetric:
+		return counterRate(a, key, float64(disk.writes))
+	case readMetric:
+		return counterRate(a, key, float64(disk.readSectors)*512/1048576)
+	case writeMetric:
+		return counterRate(a, key, float64(disk.writtenSectors)*512/1048576)
+	case awaitMetric:
+		waited, waitedReady := counterRate(a, key+":time", float64(disk.readTime+disk.writeTime))
+		operations, ready := counterRate(a, key, float64(disk.reads+disk.writes))
+		if !waitedReady || !ready || operations == 0 {
+			return 0.0, waitedReady && ready
+		}
+		return waited / operations, true
+	}
+	return 0.0, false
+}
+
+// get number of processes of the system in the given condition.
+// Percentages are calculated against the maximum PID for the total, and against the total otherwise
+func getProcesses(m metric, manager sigarMetrics, percentage bool) (float64, bool) {
+	list, err := manager.getProcList()
+	if err != nil {
+		return 0.0, false
+	}
+	total := float64(len(list.List))
+
+	value := 0.0
+	switch m {
+	case countMetric:
+		value = total
+	case zombiesMetric:
+		for _, pid := range list.List {
+			if getPidState(uint(pid), manager) == float64(Zombie) {
+				value++
+			}
+		}
+	case runningMetric, blockedMetric:
+		stat, err := manager.getSystemStat()
+		if err != nil {
+			return 0.0, false
+		}
+		value = float64(stat.running)
+		if m == blockedMetric {
+			value = float64(stat.blocked)
+		}
+	}
+
+	if !percentage {
+		return value, true
+	}
+	if m == countMetric {
+		pidMax, err := manager.getPidMax()
+		if err != nil || pidMax == 0 {
+			return 0.0, false
+		}
+		return 100.0 * value / float64(pidMax), true
+	}
+	if total == 0 {
+		return 0.0, true
+	}
+	return 100.0 * value / total, true
+}
+
+func getCpuCount(manager sigarMetrics) float64 {
+	cpus, err := manager.getCpuList()
+
+	if err != nil || len(cpus.List) == 0 {
+		return 1.0
+	}
+	return float64(len(cpus.List))
 }
 
 func getTotalMemory(manager sigarMetrics) float64 {
@@ -146,6 +496,42 @@ func getFreeSwap(manager sigarMetrics) float64 {
 	return float64(swap.Free)
 }
 
+// get a field of the memory statistics, in megabytes unless it is a number of huge pages.
+// Percentages are calculated against the commit limit for the committed memory, against the total of huge pages for them,
+// and against the total memory otherwise
+func getMemoryField(name string, manager sigarMetrics, percentage bool) (float64, bool) {
+	fields, err := manager.getMeminfo()
+	if err != nil {
+		return 0.0, false
+	}
+
+	v, found := fields[name]
+	if !found {
+		return 0.0, false
+	}
+	value := float64(v)
+	hugePages := strings.HasPrefix(name, "HugePages_")
+
+	if !percentage {
+		if hugePages {
+			return value, true
+		}
+		return value / 1048576, true
+	}
+
+	total := fields["MemTotal"]
+	switch {
+	case name == "Committed_AS":
+		total = fields["CommitLimit"]
+	case hugePages:
+		total = fields["HugePages_Total"]
+	}
+	if total == 0 {
+		return 0.0, false
+	}
+	return 100.0 * value / float64(total), true
+}
+
 func getActualUsedMemory(manager sigarMetrics, percentage bool) float64 {
 	mem, err := manager.GetMem()
 
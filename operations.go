@@ -1,5 +1,7 @@
 package golarm
 
+import "time"
+
 func setMetric(a *Alarm, v float64, m metric) {
 	a.value = value{value: v, percentage: false}
 	a.stats.metric = m
@@ -25,7 +27,51 @@ func isMetricCorrect(a *Alarm, v float64, m metric) bool {
 			if a.jobType == uptimeAlarm || a.jobType == procAlarm {
 				return true
 			}
-		case statusMetric:
+		case totalMetric:
+			if a.jobType == memoryAlarm || a.jobType == swapAlarm {
+				return true
+			}
+		case fieldMetric:
+			if a.jobType == memoryAlarm {
+				return true
+			}
+		case readMetric, writeMetric:
+			if a.jobType == procAlarm || a.jobType == diskAlarm {
+				return true
+			}
+		case utilisationMetric, readsMetric, writesMetric, awaitMetric:
+			if a.jobType == diskAlarm {
+				return true
+			}
+		case receivedBytesMetric, sentBytesMetric, receivedPacketsMetric, sentPacketsMetric, errorsMetric, dropsMetric:
+			if a.jobType == networkAlarm {
+				return true
+			}
+		case perCoreMetric:
+			if a.jobType == loadAlarm {
+				return true
+			}
+		case countMetric:
+			if a.jobType == processesAlarm || a.jobType == tcpAlarm {
+				return true
+			}
+		case listeningMetric, notListeningMetric:
+			if a.jobType == portAlarm {
+				return true
+			}
+		case establishedMetric, timeWaitMetric, closeWaitMetric, listenMetric:
+			if a.jobType == tcpAlarm {
+				return true
+			}
+		case zombiesMetric, runningMetric, blockedMetric:
+			if a.jobType == processesAlarm {
+				return true
+			}
+		case cpuMetric, cpuTimeMetric, filesMetric, threadsMetric, majorFaultsMetric:
+			if a.jobType == procAlarm {
+				return true
+			}
+		case statusMetric, restartedMetric, exitedMetric:
 			if a.jobType != alertTypeNotDefined && a.jobType == procAlarm {
 				return true
 			}
@@ -35,12 +81,41 @@ func isMetricCorrect(a *Alarm, v float64, m metric) bool {
 	return false
 }
 
+func isRangeCorrect(a *Alarm, lo, hi float64, c comparison) bool {
+	if isComparisonCorrect(a, lo, c) {
+		if lo <= hi {
+			return true
+		}
+		a.Err = ErrIncorrectRange
+	}
+	return false
+}
+
+func setRange(a *Alarm, lo, hi float64, c comparison) {
+	a.value = value{value: lo, upper: hi, percentage: false}
+	a.comparison = c
+}
+
+func isModifierCorrect(a *Alarm) bool {
+	if a.Err == nil {
+		if a.modifier != modifierNotDefined {
+			a.Err = ErrMultipleModifierDefined
+			return false
+		}
+		if a.jobType != alertTypeNotDefined && a.jobType != compositeAlarm && !isStatus(a) {
+			return true
+		}
+		a.Err = ErrIncorrectTypeForModifier
+	}
+	return false
+}
+
 func isComparisonCorrect(a *Alarm, v float64, c comparison) bool {
 	if a.Err == nil {
 		if a.comparison == comparisonNotDefined {
 			switch c {
-			case above, below, equal, belowEqual, aboveEqual:
-				if a.jobType != alertTypeNotDefined && a.stats.metric != statusMetric {
+			case above, below, equal, belowEqual, aboveEqual, notEqual, between, outside:
+				if a.jobType != alertTypeNotDefined && a.jobType != compositeAlarm && !isStatus(a) {
 					return true
 				}
 				a.Err = ErrIncorrectTypeForComparison
@@ -69,7 +144,227 @@ func (j *Alarm) Used() *Alarm {
 	return j
 }
 
-// RunningTime gets the time a process has been running
+// Total allows to specify that the created alarm will use the total memory as main metric
+func (j *Alarm) Total() *Alarm {
+	if isMetricCorrect(j, notSet, totalMetric) {
+		setMetric(j, notSet, totalMetric)
+	}
+	return j
+}
+
+// ReceivedBytes allows to specify that the created alarm will use the megabytes per second received by a network interface as main metric
+func (j *Alarm) ReceivedBytes() *Alarm {
+	if isMetricCorrect(j, notSet, receivedBytesMetric) {
+		setMetric(j, notSet, receivedBytesMetric)
+	}
+	return j
+}
+
+// SentBytes allows to specify that the created alarm will use the megabytes per second sent by a network interface as main metric
+func (j *Alarm) SentBytes() *Alarm {
+	if isMetricCorrect(j, notSet, sentBytesMetric) {
+		setMetric(j, notSet, sentBytesMetric)
+	}
+	return j
+}
+
+// ReceivedPackets allows to specify that the created alarm will use the packets per second received by a network interface as main metric
+func (j *Alarm) ReceivedPackets() *Alarm {
+	if isMetricCorrect(j, notSet, receivedPacketsMetric) {
+		setMetric(j, notSet, receivedPacketsMetric)
+	}
+	return j
+}
+
+// SentPackets allows to specify that the created alarm will use the packets per second sent by a network interface as main metric
+func (j *Alarm) SentPackets() *Alarm {
+	if isMetricCorrect(j, notSet, sentPacketsMetric) {
+		setMetric(j, notSet, sentPacketsMetric)
+	}
+	return j
+}
+
+// Errors allows to specify that the created alarm will use the errors per second of a network interface, receiving or sending, as main metric
+func (j *Alarm) Errors() *Alarm {
+	if isMetricCorrect(j, notSet, errorsMetric) {
+		setMetric(j, notSet, errorsMetric)
+	}
+	return j
+}
+
+// Drops allows to specify that the created alarm will use the packets per second dropped by a network interface, receiving or sending, as main metric
+func (j *Alarm) Drops() *Alarm {
+	if isMetricCorrect(j, notSet, dropsMetric) {
+		setMetric(j, notSet, dropsMetric)
+	}
+	return j
+}
+
+// PerCore allows to specify that the created alarm will use the load divided by the number of online CPUs,
+// so the same threshold can be used in machines with a different number of cores
+func (j *Alarm) PerCore() *Alarm {
+	if isMetricCorrect(j, notSet, perCoreMetric) {
+		setMetric(j, notSet, perCoreMetric)
+	}
+	return j
+}
+
+// Field allows to specify that the created alarm will use the given field of /proc/meminfo as main metric, like Dirty or Shmem.
+// Amounts of memory are in megabytes and their percentage is calculated against the total memory.
+// Nothing is measured when the kernel doesn't provide the field
+func (j *Alarm) Field(name string) *Alarm {
+	if isMetricCorrect(j, notSet, fieldMetric) {
+		if name == "" {
+			(*j).Err = ErrInexistentField
+			return j
+		}
+		setMetric(j, notSet, fieldMetric)
+		(*j).stats.field = name
+	}
+	return j
+}
+
+// Available allows to specify that the created alarm will use the memory available for starting new applications without swapping as main metric
+func (j *Alarm) Available() *Alarm {
+	return j.Field("MemAvailable")
+}
+
+// Cached allows to specify that the created alarm will use the memory used by the page cache as main metric
+func (j *Alarm) Cached() *Alarm {
+	return j.Field("Cached")
+}
+
+// Buffers allows to specify that the created alarm will use the memory used by block device buffers as main metric
+func (j *Alarm) Buffers() *Alarm {
+	return j.Field("Buffers")
+}
+
+// Dirty allows to specify that the created alarm will use the memory waiting to be written back to disk as main metric
+func (j *Alarm) Dirty() *Alarm {
+	return j.Field("Dirty")
+}
+
+// Writeback allows to specify that the created alarm will use the memory being written back to disk as main metric
+func (j *Alarm) Writeback() *Alarm {
+	return j.Field("Writeback")
+}
+
+// Slab allows to specify that the created alarm will use the memory used by the kernel data structures cache as main metric
+func (j *Alarm) Slab() *Alarm {
+	return j.Field("Slab")
+}
+
+// Committed allows to specify that the created alarm will use the memory committed to allocations as main metric,
+// calculating its percentage against the commit limit
+func (j *Alarm) Committed() *Alarm {
+	return j.Field("Committed_AS")
+}
+
+// HugePagesFree allows to specify that the created alarm will use the number of free huge pages as main metric,
+// calculating its percentage against the total of them
+func (j *Alarm) HugePagesFree() *Alarm {
+	return j.Field("HugePages_Free")
+}
+
+// CPU allows to specify that the created alarm will use the CPU usage of a process as main metric.
+// It is the percentage of a single core, unless Percent is used for normalising it to all of them
+func (j *Alarm) CPU() *Alarm {
+	if isMetricCorrect(j, notSet, cpuMetric) {
+		setMetric(j, notSet, cpuMetric)
+	}
+	return j
+}
+
+// FileDescriptors allows to specify that the created alarm will use the open file descriptors of a process as main metric.
+// When using a percentage, it is calculated against the soft limit of the process
+func (j *Alarm) FileDescriptors() *Alarm {
+	if isMetricCorrect(j, notSet, filesMetric) {
+		setMetric(j, notSet, filesMetric)
+	}
+	return j
+}
+
+// Threads allows to specify that the created alarm will use the number of threads of a process as main metric
+func (j *Alarm) Threads() *Alarm {
+	if isMetricCorrect(j, notSet, threadsMetric) {
+		setMetric(j, notSet, threadsMetric)
+	}
+	return j
+}
+
+// MajorFaults allows to specify that the created alarm will use the major page faults per second of a process as main metric
+func (j *Alarm) MajorFaults() *Alarm {
+	if isMetricCorrect(j, notSet, majorFaultsMetric) {
+		setMetric(j, notSet, majorFaultsMetric)
+	}
+	return j
+}
+
+// ReadBytes allows to specify that the created alarm will use the megabytes per second read from storage as main metric
+func (j *Alarm) ReadBytes() *Alarm {
+	if isMetricCorrect(j, notSet, readMetric) {
+		setMetric(j, notSet, readMetric)
+	}
+	return j
+}
+
+// WrittenBytes allows to specify that the created alarm will use the megabytes per second written to storage as main metric
+func (j *Alarm) WrittenBytes() *Alarm {
+	if isMetricCorrect(j, notSet, writeMetric) {
+		setMetric(j, notSet, writeMetric)
+	}
+	return j
+}
+
+// Utilisation allows to specify that the created alarm will use the percentage of time a disk is busy as main metric
+func (j *Alarm) Utilisation() *Alarm {
+	if isMetricCorrect(j, notSet, utilisationMetric) {
+		setMetric(j, notSet, utilisationMetric)
+	}
+	return j
+}
+
+// Reads allows to specify that the created alarm will use the read operations per second of a disk as main metric
+func (j *Alarm) Reads() *Alarm {
+	if isMetricCorrect(j, notSet, readsMetric) {
+		setMetric(j, notSet, readsMetric)
+	}
+	return j
+}
+
+// Writes allows to specify that the created alarm will use the write operations per second of a disk as main metric
+func (j *Alarm) Writes() *Alarm {
+	if isMetricCorrect(j, notSet, writesMetric) {
+		setMetric(j, notSet, writesMetric)
+	}
+	return j
+}
+
+// Await allows to specify that the created alarm will use the average milliseconds taken by the operations of a disk as main metric
+func (j *Alarm) Await() *Alarm {
+	if isMetricCorrect(j, notSet, awaitMetric) {
+		setMetric(j, notSet, awaitMetric)
+	}
+	return j
+}
+
+// Restarted fires when the process is restarted, so its PID or start time are different than in the previous check
+func (j *Alarm) Restarted() *Alarm {
+	if isMetricCorrect(j, notSet, restartedMetric) && isEventCorrect(j) {
+		setMetric(j, notSet, restartedMetric)
+	}
+	return j
+}
+
+// Exited fires when the process found in the previous check doesn't exist anymore
+func (j *Alarm) Exited() *Alarm {
+	if isMetricCorrect(j, notSet, exitedMetric) && isEventCorrect(j) {
+		setMetric(j, notSet, exitedMetric)
+	}
+	return j
+}
+
+// RunningTime gets the time a process has been running, in minutes since it was started
 func (j *Alarm) RunningTime() *Alarm {
 	if isMetricCorrect(j, notSet, timeMetric) {
 		setMetric(j, notSet, timeMetric)
@@ -77,11 +372,341 @@ func (j *Alarm) RunningTime() *Alarm {
 	return j
 }
 
+// CPUTime gets the CPU time a process has consumed, in minutes
+func (j *Alarm) CPUTime() *Alarm {
+	if isMetricCorrect(j, notSet, cpuTimeMetric) {
+		setMetric(j, notSet, cpuTimeMetric)
+	}
+	return j
+}
+
+func setStatus(a *Alarm, s []state, exclude bool) {
+	if isMetricCorrect(a, notSet, statusMetric) {
+		if a.stats.proc.aggregation == sumAggregation || a.stats.proc.aggregation == maxAggregation {
+			a.Err = ErrIncorrectTypeForAggregation
+			return
+		}
+		if len(s) == 0 {
+			a.Err = ErrEmptyStatus
+			return
+		}
+		setMetric(a, notSet, statusMetric)
+		a.stats.proc.states = s
+		a.stats.proc.exclude = exclude
+	}
+}
+
 // Status allows to specify the state for a given process
 func (j *Alarm) Status(s state) *Alarm {
+	setStatus(j, []state{s}, false)
+	return j
+}
+
+// StatusIn allows to specify several states for a given process, firing when it is in any of them
+func (j *Alarm) StatusIn(s ...state) *Alarm {
+	setStatus(j, s, false)
+	return j
+}
+
+// StatusNot allows to specify several states for a given process, firing when it is in none of them
+func (j *Alarm) StatusNot(s ...state) *Alarm {
+	setStatus(j, s, true)
+	return j
+}
+
+// StatusChanged fires when the state of a given process is different than in the previous check
+func (j *Alarm) StatusChanged() *Alarm {
 	if isMetricCorrect(j, notSet, statusMetric) {
+		if isGroup(j) {
+			(*j).Err = ErrIncorrectTypeForAggregation
+			return j
+		}
 		setMetric(j, notSet, statusMetric)
-		(*j).stats.proc.state = s
+		(*j).stats.proc.changed = true
+	}
+	return j
+}
+
+// Rate allows to compare the rate of change of the metric per minute instead of its current value
+func (j *Alarm) Rate() *Alarm {
+	if isModifierCorrect(j) {
+		(*j).modifier = rateModifier
+	}
+	return j
+}
+
+// Delta allows to compare how much the metric has changed during the given window instead of its current value
+func (j *Alarm) Delta(window time.Duration) *Alarm {
+	setWindow(j, window, deltaModifier)
+	return j
+}
+
+func setWindow(a *Alarm, window time.Duration, m modifier) {
+	if isModifierCorrect(a) {
+		if window <= 0 {
+			a.Err = ErrIncorrectWindow
+			return
+		}
+		a.modifier = m
+		a.window = window
+	}
+}
+
+// AvgOver allows to compare the average of the metric during the given window instead of its current value.
+// Like the rest of aggregations over a window, it doesn't fire until the first window is complete
+func (j *Alarm) AvgOver(window time.Duration) *Alarm {
+	setWindow(j, window, avgModifier)
+	return j
+}
+
+// MinOver allows to compare the minimum value of the metric during the given window instead of its current value
+func (j *Alarm) MinOver(window time.Duration) *Alarm {
+	setWindow(j, window, minModifier)
+	return j
+}
+
+// MaxOver allows to compare the maximum value of the metric during the given window instead of its current value
+func (j *Alarm) MaxOver(window time.Duration) *Alarm {
+	setWindow(j, window, maxModifier)
+	return j
+}
+
+// PercentileOver allows to compare the given percentile (0.95 for the 95th) of the metric during the window instead of its current value
+func (j *Alarm) PercentileOver(p float64, window time.Duration) *Alarm {
+	if j.Err == nil && (p <= 0 || p > 1) {
+		(*j).Err = ErrIncorrectPercentile
+		return j
+	}
+	setWindow(j, window, percentileModifier)
+	(*j).percentile = p
+	return j
+}
+
+// TrendOver allows to specify the window used for fitting the trend of a prediction.
+// By default the trend is fitted over a window as long as the prediction horizon
+func (j *Alarm) TrendOver(window time.Duration) *Alarm {
+	setWindow(j, window, predictModifier)
+	return j
+}
+
+func setPrediction(a *Alarm, v float64, horizon time.Duration, c comparison) {
+	if a.modifier != predictModifier && !isModifierCorrect(a) {
+		return
+	}
+	if horizon <= 0 {
+		a.Err = ErrIncorrectHorizon
+		return
+	}
+	if isComparisonCorrect(a, v, c) {
+		setComparison(a, v, c)
+		if a.modifier != predictModifier {
+			a.modifier = predictModifier
+			a.window = horizon
+		}
+		a.horizon = horizon
+	}
+}
+
+// PredictBelow compares if the trend of the metric forecasts it to be lower than the number set once the horizon has passed
+func (j *Alarm) PredictBelow(v float64, horizon time.Duration) *Alarm {
+	setPrediction(j, v, horizon, below)
+	return j
+}
+
+// PredictAbove compares if the trend of the metric forecasts it to be greater than the number set once the horizon has passed
+func (j *Alarm) PredictAbove(v float64, horizon time.Duration) *Alarm {
+	setPrediction(j, v, horizon, above)
+	return j
+}
+
+// Anomalous compares if the metric deviates more than k standard deviations from its mean during the window.
+// The alarm warms up without firing until the first window has been completed
+func (j *Alarm) Anomalous(k float64, window time.Duration) *Alarm {
+	if j.Err == nil && k <= 0 {
+		(*j).Err = ErrIncorrectDeviation
+		return j
+	}
+	setWindow(j, window, anomalyModifier)
+	if isComparisonCorrect(j, k, above) {
+		setComparison(j, k, above)
+	}
+	return j
+}
+
+func isEventCorrect(a *Alarm) bool {
+	if isGroup(a) {
+		a.Err = ErrIncorrectTypeForAggregation
+		return false
+	}
+	return true
+}
+
+func setAggregation(a *Alarm, g aggregation) {
+	if a.Err == nil {
+		if a.stats.proc.aggregation != aggregationNotDefined {
+			a.Err = ErrMultipleAggregationDefined
+			return
+		}
+		if a.jobType != procAlarm || a.stats.metric == restartedMetric || a.stats.metric == exitedMetric ||
+			(a.stats.metric == statusMetric && (g != countAggregation || a.stats.proc.changed)) {
+			a.Err = ErrIncorrectTypeForAggregation
+			return
+		}
+		a.stats.proc.aggregation = g
+	}
+}
+
+// WithChildren allows to include all the descendants of the selected processes, adding up their metrics unless another aggregation is used
+func (j *Alarm) WithChildren() *Alarm {
+	if j.Err == nil {
+		// the state of the processes can still be counted, which is checked once the alarm is built
+		if j.jobType != procAlarm || j.stats.metric == restartedMetric || j.stats.metric == exitedMetric || j.stats.proc.changed {
+			(*j).Err = ErrIncorrectTypeForAggregation
+			return j
+		}
+		(*j).stats.proc.descendants = true
+	}
+	return j
+}
+
+// Sum allows to use the sum of the metric over all the selected processes
+func (j *Alarm) Sum() *Alarm {
+	setAggregation(j, sumAggregation)
+	return j
+}
+
+// Max allows to use the highest value of the metric among all the selected processes
+func (j *Alarm) Max() *Alarm {
+	setAggregation(j, maxAggregation)
+	return j
+}
+
+// Count allows to use the number of selected processes, or the number of them in the given states when used with Status.
+// With SystemProcesses it is the number of processes of the system, and its percentage is calculated against the maximum PID.
+// With SystemTCP it is the number of sockets
+func (j *Alarm) Count() *Alarm {
+	if j.jobType == processesAlarm || j.jobType == tcpAlarm {
+		if isMetricCorrect(j, notSet, countMetric) {
+			setMetric(j, notSet, countMetric)
+		}
+		return j
+	}
+	setAggregation(j, countAggregation)
+	return j
+}
+
+// Established allows to specify that the created alarm will use the number of established TCP connections as main metric
+func (j *Alarm) Established() *Alarm {
+	if isMetricCorrect(j, notSet, establishedMetric) {
+		setMetric(j, notSet, establishedMetric)
+	}
+	return j
+}
+
+// TimeWait allows to specify that the created alarm will use the number of TCP sockets in TIME_WAIT as main metric
+func (j *Alarm) TimeWait() *Alarm {
+	if isMetricCorrect(j, notSet, timeWaitMetric) {
+		setMetric(j, notSet, timeWaitMetric)
+	}
+	return j
+}
+
+// CloseWait allows to specify that the created alarm will use the number of TCP sockets in CLOSE_WAIT as main metric
+func (j *Alarm) CloseWait() *Alarm {
+	if isMetricCorrect(j, notSet, closeWaitMetric) {
+		setMetric(j, notSet, closeWaitMetric)
+	}
+	return j
+}
+
+// Listen allows to specify that the created alarm will use the number of listening TCP sockets as main metric
+func (j *Alarm) Listen() *Alarm {
+	if isMetricCorrect(j, notSet, listenMetric) {
+		setMetric(j, notSet, listenMetric)
+	}
+	return j
+}
+
+// Port allows to specify that only the sockets with the given local port are used
+func (j *Alarm) Port(port int) *Alarm {
+	if j.Err == nil {
+		if j.jobType != tcpAlarm {
+			(*j).Err = ErrIncorrectTypeForPort
+			return j
+		}
+		if port < 1 || port > 65535 {
+			(*j).Err = ErrIncorrectPort
+			return j
+		}
+		(*j).stats.port = port
+	}
+	return j
+}
+
+// Listening fires when there is a socket bound to the port
+func (j *Alarm) Listening() *Alarm {
+	if isMetricCorrect(j, notSet, listeningMetric) {
+		setMetric(j, notSet, listeningMetric)
+	}
+	return j
+}
+
+// NotListening fires when there is no socket bound to the port, like when a service drops its listener while still running
+func (j *Alarm) NotListening() *Alarm {
+	if isMetricCorrect(j, notSet, notListeningMetric) {
+		setMetric(j, notSet, notListeningMetric)
+	}
+	return j
+}
+
+// UDP allows to specify that the port is a UDP one
+func (j *Alarm) UDP() *Alarm {
+	if j.Err == nil {
+		if j.jobType != portAlarm {
+			(*j).Err = ErrIncorrectTypeForPort
+			return j
+		}
+		(*j).stats.protocol = "udp"
+	}
+	return j
+}
+
+// OwnedBy allows to specify that the socket bound to the port must belong to the process with the given PID
+func (j *Alarm) OwnedBy(pid uint) *Alarm {
+	if j.Err == nil {
+		if j.jobType != portAlarm {
+			(*j).Err = ErrIncorrectTypeForPort
+			return j
+		}
+		if !pidExists(int(pid)) {
+			(*j).Err = ErrInexistentPid
+			return j
+		}
+		(*j).stats.proc.pid = pid
+	}
+	return j
+}
+
+// Zombies allows to specify that the created alarm will use the number of zombie processes of the system as main metric
+func (j *Alarm) Zombies() *Alarm {
+	if isMetricCorrect(j, notSet, zombiesMetric) {
+		setMetric(j, notSet, zombiesMetric)
+	}
+	return j
+}
+
+// Running allows to specify that the created alarm will use the number of running processes of the system as main metric
+func (j *Alarm) Running() *Alarm {
+	if isMetricCorrect(j, notSet, runningMetric) {
+		setMetric(j, notSet, runningMetric)
+	}
+	return j
+}
+
+// Blocked allows to specify that the created alarm will use the number of processes of the system blocked on I/O as main metric
+func (j *Alarm) Blocked() *Alarm {
+	if isMetricCorrect(j, notSet, blockedMetric) {
+		setMetric(j, notSet, blockedMetric)
 	}
 	return j
 }
@@ -126,6 +751,46 @@ func (j *Alarm) Below(v float64) *Alarm {
 	return j
 }
 
+// NotEqual compares if the specified alarm is different than the number set
+func (j *Alarm) NotEqual(v float64) *Alarm {
+	if isComparisonCorrect(j, v, notEqual) {
+		setComparison(j, v, notEqual)
+	}
+	return j
+}
+
+// Within allows a tolerance to be used with Equal and NotEqual, so values closer than eps are considered equal
+func (j *Alarm) Within(eps float64) *Alarm {
+	if j.Err == nil {
+		if j.comparison != equal && j.comparison != notEqual {
+			(*j).Err = ErrIncorrectTypeForTolerance
+			return j
+		}
+		if eps < 0 {
+			(*j).Err = ErrIncorrectTolerance
+			return j
+		}
+		(*j).value.tolerance = eps
+	}
+	return j
+}
+
+// Between compares if the specified alarm is inside the range set, both bounds included
+func (j *Alarm) Between(lo, hi float64) *Alarm {
+	if isRangeCorrect(j, lo, hi, between) {
+		setRange(j, lo, hi, between)
+	}
+	return j
+}
+
+// Outside compares if the specified alarm is out of the range set
+func (j *Alarm) Outside(lo, hi float64) *Alarm {
+	if isRangeCorrect(j, lo, hi, outside) {
+		setRange(j, lo, hi, outside)
+	}
+	return j
+}
+
 func parsePercentage(percent float64) (float64, error) {
 	if percent > 100 || percent < 0 {
 		return 0.0, ErrIncorrectValuesWithPercentage
@@ -140,17 +805,23 @@ func (j *Alarm) Percent() *Alarm {
 			(*j).Err = ErrExpectedNumWhenPercentage
 			return j
 		}
-		if j.jobType == uptimeAlarm || j.stats.metric == statusMetric {
+		if j.jobType == uptimeAlarm || j.jobType == expressionAlarm || j.jobType == networkAlarm || j.jobType == diskAlarm || isStatus(j) ||
+			j.modifier == anomalyModifier ||
+			(j.stats.proc.aggregation == countAggregation && j.stats.proc.selector != byUser) {
 			(*j).Err = ErrIncorrectTypeForPercentage
 			return j
 		}
 
 		val, err := parsePercentage(j.value.value)
+		if err == nil && (j.comparison == between || j.comparison == outside) {
+			_, err = parsePercentage(j.value.upper)
+		}
 
 		if err != nil {
 			(*j).Err = err
 		} else {
-			(*j).value = value{value: val, percentage: true}
+			(*j).value.value = val
+			(*j).value.percentage = true
 		}
 	}
 	return j
@@ -0,0 +1,259 @@
+package golarm
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type selector int
+type aggregation int
+
+const (
+	byPid selector = iota
+	byName
+	byPattern
+	byPidfile
+	byUser
+)
+
+const (
+	aggregationNotDefined aggregation = iota
+	sumAggregation
+	maxAggregation
+	countAggregation
+)
+
+// commLength is the maximum length of the process names kept by the kernel, longer ones are truncated
+const commLength = 15
+
+// comm returns the name as the kernel reports it for a process
+func comm(name string) string {
+	if len(name) > commLength {
+		return name[:commLength]
+	}
+	return name
+}
+
+// pids returns the PIDs of the processes selected by the alarm, sorted in ascending order
+func pids(a *Alarm) []int {
+	p := a.stats.proc
+	manager := a.metricsManager
+
+	switch p.selector {
+	case byPid:
+		if _, err := manager.getProcState(int(p.pid)); err != nil {
+			return nil
+		}
+		return []int{int(p.pid)}
+
+	case byPidfile:
+		content, err := ioutil.ReadFile(p.pidfile)
+		if err != nil {
+			return nil
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return nil
+		}
+		if _, err := manager.getProcState(pid); err != nil {
+			return nil
+		}
+		return []int{pid}
+	}
+
+	list, err := manager.getProcList()
+	if err != nil {
+		return nil
+	}
+
+	matching := make([]int, 0)
+	for _, pid := range list.List {
+		switch p.selector {
+		case byName:
+			state, err := manager.getProcState(pid)
+			if err == nil && state.Name == comm(p.name) {
+				matching = append(matching, pid)
+			}
+		case byPattern:
+			args, err := manager.getProcArgs(pid)
+			if err == nil && p.pattern.MatchString(strings.Join(args.List, " ")) {
+				matching = append(matching, pid)
+			}
+		case byUser:
+			status, err := manager.getProcStatus(pid)
+			if err == nil && status.uid == p.uid {
+				matching = append(matching, pid)
+			}
+		}
+	}
+	sort.Ints(matching)
+	return matching
+}
+
+// locate finds the process monitored by the alarm, using the lowest PID when several of them are selected.
+// It returns false, executing the NoMatch callback, when there is no process
+func locate(a *Alarm) bool {
+	if a.stats.proc.selector == byPid {
+		return true
+	}
+
+	matching := pids(a)
+	if len(matching) == 0 {
+		if a.noMatch != nil {
+			a.noMatch()
+		}
+		return false
+	}
+	a.stats.proc.pid = uint(matching[0])
+	return true
+}
+
+// isGroup checks if the alarm is based on a group of processes instead of a single one
+func isGroup(a *Alarm) bool {
+	p := a.stats.proc
+	return p.aggregation != aggregationNotDefined || p.descendants || p.selector == byUser
+}
+
+// descendants returns the given processes together with all their descendants, sorted in ascending order
+func descendants(a *Alarm, roots []int) []int {
+	list, err := a.metricsManager.getProcList()
+	if err != nil {
+		return roots
+	}
+
+	children := make(map[int][]int)
+	for _, pid := range list.List {
+		state, err := a.metricsManager.getProcState(pid)
+		if err == nil && state.Ppid != pid {
+			children[state.Ppid] = append(children[state.Ppid], pid)
+		}
+	}
+
+	found := make(map[int]bool)
+	pending := append([]int{}, roots...)
+	for len(pending) > 0 {
+		pid := pending[0]
+		pending = pending[1:]
+		if found[pid] {
+			continue
+		}
+		found[pid] = true
+		pending = append(pending, children[pid]...)
+	}
+
+	tree := make([]int, 0, len(found))
+	for pid := range found {
+		tree = append(tree, pid)
+	}
+	sort.Ints(tree)
+	return tree
+}
+
+// aggregate gets the metric over all the selected processes, adding them up by default.
+// No value is returned when there are no processes, unless they are being counted,
+// and processes whose value can't be calculated yet are skipped
+func aggregate(a *Alarm) (float64, bool) {
+	matching := pids(a)
+	p := &a.stats.proc
+	if p.descendants && len(matching) > 0 {
+		matching = descendants(a, matching)
+	}
+
+	if p.aggregation == countAggregation {
+		counted := make([]int, 0, len(matching))
+		for _, pid := range matching {
+			if a.stats.metric != statusMetric || inStates(p, state(getPidState(uint(pid), a.metricsManager))) {
+				counted = append(counted, pid)
+			}
+		}
+		if a.value.percentage {
+			return countPercentage(a, matching, counted)
+		}
+		return float64(len(counted)), true
+	}
+
+	if len(matching) == 0 {
+		if a.noMatch != nil {
+			a.noMatch()
+		}
+		return 0.0, false
+	}
+
+	value := 0.0
+	measured := false
+	for _, pid := range matching {
+		v, ready := measureProc(a, uint(pid))
+		if !ready {
+			continue
+		}
+		switch {
+		case p.aggregation != maxAggregation:
+			value += v
+		case !measured || v > value:
+			value = v
+		}
+		measured = true
+	}
+	return value, measured
+}
+
+func isAlive(a *Alarm, pid uint) bool {
+	_, err := a.metricsManager.getProcState(int(pid))
+	return err == nil
+}
+
+// restarted checks if the process located is a different one than in the previous check
+func restarted(a *Alarm) bool {
+	if !locate(a) {
+		return false
+	}
+
+	p := &a.stats.proc
+	t, err := a.metricsManager.getProcTime(int(p.pid))
+	if err != nil {
+		return false
+	}
+
+	fired := p.started != 0 && (p.started != t.StartTime || p.startedPid != p.pid)
+	p.started = t.StartTime
+	p.startedPid = p.pid
+	return fired
+}
+
+// exited checks if the process watched since the previous check has disappeared.
+// Processes selected by name, pattern or pidfile are looked up again, so a new one is watched after a restart
+func exited(a *Alarm) bool {
+	p := &a.stats.proc
+	gone := p.watching && !isAlive(a, p.pid)
+
+	p.watching = locate(a) && isAlive(a, p.pid)
+	return gone
+}
+
+// countPercentage calculates the percentage of the limit of processes of the user, which is read from the first one of them.
+// As the kernel applies that limit to threads, the threads of the counted processes are used
+func countPercentage(a *Alarm, matching []int, counted []int) (float64, bool) {
+	if len(matching) == 0 {
+		return 0.0, true
+	}
+
+	limit, err := a.metricsManager.getProcSoftLimit(matching[0], "Max processes")
+	if err != nil {
+		return 0.0, false
+	}
+	if limit == 0 {
+		return 0.0, true
+	}
+
+	threads := uint64(0)
+	for _, pid := range counted {
+		status, err := a.metricsManager.getProcStatus(pid)
+		if err != nil {
+			continue
+		}
+		threads += status.threads
+	}
+	return 100.0 * float64(threads) / float64(limit), true
+}
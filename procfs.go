@@ -0,0 +1,314 @@
+package golarm
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProcRoot is the path where procfs is mounted, used for the metrics not provided by sigar
+var ProcRoot = "/proc"
+
+var columns = regexp.MustCompile(`\s{2,}`)
+
+type procFiles struct {
+	open  uint64
+	limit uint64
+}
+
+type procStatus struct {
+	threads uint64
+	uid     int
+}
+
+type systemStat struct {
+	running uint64
+	blocked uint64
+}
+
+type procIO struct {
+	readBytes  uint64
+	writeBytes uint64
+}
+
+type netDevice struct {
+	receivedBytes   uint64
+	receivedPackets uint64
+	receivedErrors  uint64
+	receivedDrops   uint64
+	sentBytes       uint64
+	sentPackets     uint64
+	sentErrors      uint64
+	sentDrops       uint64
+}
+
+type socket struct {
+	port  int
+	state int
+	inode uint64
+}
+
+// states of the sockets in procfs
+const (
+	tcpEstablished = 0x01
+	tcpTimeWait    = 0x06
+	tcpClose       = 0x07
+	tcpCloseWait   = 0x08
+	tcpListen      = 0x0A
+)
+
+type diskStats struct {
+	reads          uint64
+	readSectors    uint64
+	readTime       uint64
+	writes         uint64
+	writtenSectors uint64
+	writeTime      uint64
+	ioTime         uint64
+}
+
+func procPath(root string, pid int, name string) string {
+	return filepath.Join(root, strconv.Itoa(pid), name)
+}
+
+// readLines calls f with every line of the file until it returns false
+func readLines(path string, f func(string) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if !f(scanner.Text()) {
+			break
+		}
+	}
+	return scanner.Err()
+}
+
+// readSoftLimit gets the soft limit with the given name from the limits of a process, being 0 when unlimited
+func readSoftLimit(root string, pid int, name string) (uint64, error) {
+	limit := uint64(0)
+	err := readLines(procPath(root, pid, "limits"), func(line string) bool {
+		fields := columns.Split(strings.TrimSpace(line), -1)
+		if len(fields) < 2 || fields[0] != name {
+			return true
+		}
+		limit, _ = strconv.ParseUint(fields[1], 10, 64)
+		return false
+	})
+	return limit, err
+}
+
+func readProcFiles(root string, pid int) (procFiles, error) {
+	files := procFiles{}
+	fds, err := ioutil.ReadDir(procPath(root, pid, "fd"))
+	if err != nil {
+		return files, err
+	}
+	files.open = uint64(len(fds))
+	files.limit, err = readSoftLimit(root, pid, "Max open files")
+	return files, err
+}
+
+// readProcSockets gets the inodes of the sockets opened by a process
+func readProcSockets(root string, pid int) ([]uint64, error) {
+	dir := procPath(root, pid, "fd")
+	fds, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	inodes := []uint64{}
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(dir, fd.Name()))
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+		if err == nil {
+			inodes = append(inodes, inode)
+		}
+	}
+	return inodes, nil
+}
+
+func readProcStatus(root string, pid int) (procStatus, error) {
+	status := procStatus{}
+	err := readLines(procPath(root, pid, "status"), func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return true
+		}
+		switch fields[0] {
+		case "Threads:":
+			status.threads, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "Uid:":
+			status.uid, _ = strconv.Atoi(fields[1])
+		}
+		return true
+	})
+	return status, err
+}
+
+func readProcIO(root string, pid int) (procIO, error) {
+	io := procIO{}
+	err := readLines(procPath(root, pid, "io"), func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return true
+		}
+		switch fields[0] {
+		case "read_bytes:":
+			io.readBytes, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "write_bytes:":
+			io.writeBytes, _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+		return true
+	})
+	return io, err
+}
+
+func readSystemStat(root string) (systemStat, error) {
+	stat := systemStat{}
+	err := readLines(filepath.Join(root, "stat"), func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return true
+		}
+		switch fields[0] {
+		case "procs_running":
+			stat.running, _ = strconv.ParseUint(fields[1], 10, 64)
+		case "procs_blocked":
+			stat.blocked, _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+		return true
+	})
+	return stat, err
+}
+
+func readPidMax(root string) (uint64, error) {
+	content, err := ioutil.ReadFile(filepath.Join(root, "sys", "kernel", "pid_max"))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
+
+// readNetDevice gets the counters of the network interface with the given name from the statistics of all of them
+func readNetDevice(root string, name string) (netDevice, error) {
+	device := netDevice{}
+	found := false
+	err := readLines(filepath.Join(root, "net", "dev"), func(line string) bool {
+		fields := strings.Fields(strings.Replace(line, ":", " ", 1))
+		if len(fields) < 17 || fields[0] != name {
+			return true
+		}
+		counters := make([]uint64, len(fields))
+		for i, f := range fields[1:] {
+			counters[i+1], _ = strconv.ParseUint(f, 10, 64)
+		}
+		device = netDevice{
+			receivedBytes:   counters[1],
+			receivedPackets: counters[2],
+			receivedErrors:  counters[3],
+			receivedDrops:   counters[4],
+			sentBytes:       counters[9],
+			sentPackets:     counters[10],
+			sentErrors:      counters[11],
+			sentDrops:       counters[12],
+		}
+		found = true
+		return false
+	})
+	if err == nil && !found {
+		err = ErrInexistentInterface
+	}
+	return device, err
+}
+
+// readSockets gets the sockets of the given protocol, like tcp or udp, both from IPv4 and IPv6.
+// A missing IPv6 table is ignored, as it is not there when IPv6 is disabled
+func readSockets(root string, protocol string) ([]socket, error) {
+	sockets := []socket{}
+	for _, name := range []string{protocol, protocol + "6"} {
+		err := readLines(filepath.Join(root, "net", name), func(line string) bool {
+			fields := strings.Fields(line)
+			if len(fields) < 10 || fields[0] == "sl" {
+				return true
+			}
+			local := strings.Split(fields[1], ":")
+			port, err := strconv.ParseUint(local[len(local)-1], 16, 16)
+			if err != nil {
+				return true
+			}
+			state, _ := strconv.ParseUint(fields[3], 16, 8)
+			inode, _ := strconv.ParseUint(fields[9], 10, 64)
+			sockets = append(sockets, socket{port: int(port), state: int(state), inode: inode})
+			return true
+		})
+		if err != nil && !(os.IsNotExist(err) && name != protocol) {
+			return nil, err
+		}
+	}
+	return sockets, nil
+}
+
+// readDiskStats gets the counters of the block device with the given name, where times are in milliseconds
+// and sectors are always 512 bytes long
+func readDiskStats(root string, name string) (diskStats, error) {
+	stats := diskStats{}
+	found := false
+	err := readLines(filepath.Join(root, "diskstats"), func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) < 14 || fields[2] != name {
+			return true
+		}
+		counters := make([]uint64, len(fields))
+		for i, f := range fields[3:] {
+			counters[i+3], _ = strconv.ParseUint(f, 10, 64)
+		}
+		stats = diskStats{
+			reads:          counters[3],
+			readSectors:    counters[5],
+			readTime:       counters[6],
+			writes:         counters[7],
+			writtenSectors: counters[9],
+			writeTime:      counters[10],
+			ioTime:         counters[12],
+		}
+		found = true
+		return false
+	})
+	if err == nil && !found {
+		err = ErrInexistentDisk
+	}
+	return stats, err
+}
+
+// readMeminfo gets the fields of the memory statistics, in bytes when they are an amount of memory
+func readMeminfo(root string) (map[string]uint64, error) {
+	fields := map[string]uint64{}
+	err := readLines(filepath.Join(root, "meminfo"), func(line string) bool {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			return true
+		}
+		v, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return true
+		}
+		if len(parts) > 2 && parts[2] == "kB" {
+			v *= 1024
+		}
+		fields[strings.TrimSuffix(parts[0], ":")] = v
+		return true
+	})
+	return fields, err
+}